@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+// caldavSource reads events from a CalDAV calendar collection (Nextcloud,
+// Radicale, ...) with a calendar-query REPORT (RFC 4791 section 7.8).
+type caldavSource struct {
+	client *http.Client
+	url    string
+	// Basic auth is used when username is set, Bearer auth when token is set.
+	// Credentials in the userinfo of url take precedence over both.
+	username string
+	password string
+	token    string
+}
+
+func newCalDAVSource(rawURL, username, password, token string) (*caldavSource, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CalDAV URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid CalDAV URL %q: scheme must be http or https", rawURL)
+	}
+	s := &caldavSource{
+		client:   &http.Client{Timeout: time.Minute},
+		username: username,
+		password: password,
+		token:    token,
+	}
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+		u.User = nil
+	}
+	s.url = u.String()
+	return s, nil
+}
+
+func (s *caldavSource) name() string {
+	return s.url
+}
+
+const caldavQueryTemplate = `<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop>
+    <C:calendar-data/>
+  </D:prop>
+  <C:filter>
+    <C:comp-filter name="VCALENDAR">
+      <C:comp-filter name="VEVENT">
+        <C:time-range start="%s" end="%s"/>
+      </C:comp-filter>
+    </C:comp-filter>
+  </C:filter>
+</C:calendar-query>
+`
+
+// caldavMultistatus is the part of a 207 Multi-Status response we need.
+type caldavMultistatus struct {
+	Responses []struct {
+		Href      string `xml:"href"`
+		Propstats []struct {
+			Status       string `xml:"status"`
+			CalendarData string `xml:"prop>calendar-data"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+func (s *caldavSource) fetchEvents(
+	ctx context.Context,
+	startDate, endDate time.Time,
+	loc *time.Location,
+) ([]*calendar.Event, error) {
+	timeMin, timeMax := queryRange(startDate, endDate, loc)
+	body := fmt.Sprintf(caldavQueryTemplate,
+		timeMin.UTC().Format(icsUTCDateTimeFormat), timeMax.UTC().Format(icsUTCDateTimeFormat))
+
+	req, err := http.NewRequestWithContext(ctx, "REPORT", s.url, bytes.NewBufferString(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+	switch {
+	case s.username != "":
+		req.SetBasicAuth(s.username, s.password)
+	case s.token != "":
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("caldav REPORT: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var ms caldavMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("caldav REPORT: invalid response: %w", err)
+	}
+
+	var events []*calendar.Event
+	for _, r := range ms.Responses {
+		for _, ps := range r.Propstats {
+			if ps.CalendarData == "" {
+				continue
+			}
+			evs, err := readICSEvents(strings.NewReader(ps.CalendarData), timeMin, timeMax, loc)
+			if err != nil {
+				return nil, fmt.Errorf("caldav %s: %w", r.Href, err)
+			}
+			events = append(events, evs...)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const caldavTestResponse = `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav">
+  <d:response>
+    <d:href>/dav/calendars/alice/work/meeting.ics</d:href>
+    <d:propstat>
+      <d:prop>
+        <cal:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:meeting@example.com
+DTSTART:20250113T010000Z
+DTEND:20250113T020000Z
+SUMMARY:Meeting
+END:VEVENT
+END:VCALENDAR
+</cal:calendar-data>
+      </d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+  <d:response>
+    <d:href>/dav/calendars/alice/work/weekly.ics</d:href>
+    <d:propstat>
+      <d:prop>
+        <cal:calendar-data>BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:weekly@example.com
+DTSTART;TZID=Asia/Tokyo:20250106T150000
+DTEND;TZID=Asia/Tokyo:20250106T160000
+RRULE:FREQ=WEEKLY
+SUMMARY:Weekly sync
+END:VEVENT
+END:VCALENDAR
+</cal:calendar-data>
+      </d:prop>
+      <d:status>HTTP/1.1 200 OK</d:status>
+    </d:propstat>
+  </d:response>
+</d:multistatus>
+`
+
+func newCalDAVTestServer(t *testing.T, checkAuth func(r *http.Request) bool) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !checkAuth(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != "REPORT" || r.Header.Get("Depth") != "1" ||
+			!strings.Contains(string(body), `<C:time-range start="20250112T150000Z" end="20250113T150000Z"/>`) {
+			t.Errorf("unexpected request: %s Depth=%q body=%s", r.Method, r.Header.Get("Depth"), body)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(http.StatusMultiStatus)
+		io.WriteString(w, caldavTestResponse)
+	}))
+}
+
+func TestCalDAVSource(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	day := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		name  string
+		url   func(base string) string
+		user  string
+		pass  string
+		token string
+		auth  func(r *http.Request) bool
+	}{
+		{
+			name: "basic auth",
+			url:  func(base string) string { return base + "/dav/calendars/alice/work/" },
+			user: "alice",
+			pass: "secret",
+			auth: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "alice" && p == "secret"
+			},
+		},
+		{
+			name: "basic auth in URL",
+			url: func(base string) string {
+				return strings.Replace(base, "http://", "http://bob:pw@", 1) + "/dav/calendars/bob/work/"
+			},
+			auth: func(r *http.Request) bool {
+				u, p, ok := r.BasicAuth()
+				return ok && u == "bob" && p == "pw"
+			},
+		},
+		{
+			name:  "bearer auth",
+			url:   func(base string) string { return base + "/dav/calendars/alice/work/" },
+			token: "tok",
+			auth: func(r *http.Request) bool {
+				return r.Header.Get("Authorization") == "Bearer tok"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newCalDAVTestServer(t, tt.auth)
+			defer srv.Close()
+
+			src, err := newCalDAVSource(tt.url(srv.URL), tt.user, tt.pass, tt.token)
+			if err != nil {
+				t.Fatalf("newCalDAVSource() error = %v", err)
+			}
+			if strings.Contains(src.name(), "@") {
+				t.Errorf("name() = %q, want credentials removed", src.name())
+			}
+			events, err := src.fetchEvents(context.Background(), day, day, loc)
+			if err != nil {
+				t.Fatalf("fetchEvents() error = %v", err)
+			}
+			got := findFreeSlots(
+				time.Date(2025, 1, 13, 9, 0, 0, 0, loc),
+				time.Date(2025, 1, 13, 17, 0, 0, 0, loc),
+				eventsToIntervals(events, loc, eventOptions{}), slotOptions{minDur: time.Hour})
+			want := []string{"09:00~10:00", "11:00~15:00", "16:00~17:00"}
+			if len(got) != len(want) {
+				t.Fatalf("findFreeSlots() = %v, want %v", got, want)
+			}
+			for i, s := range got {
+				if formatSlot(s) != want[i] {
+					t.Errorf("slot %d = %s, want %s", i, formatSlot(s), want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCalDAVSourceErrors(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	day := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+
+	srv := newCalDAVTestServer(t, func(*http.Request) bool { return false })
+	defer srv.Close()
+
+	src, err := newCalDAVSource(srv.URL, "", "", "")
+	if err != nil {
+		t.Fatalf("newCalDAVSource() error = %v", err)
+	}
+	if _, err := src.fetchEvents(context.Background(), day, day, loc); err == nil {
+		t.Error("fetchEvents() error = nil, want error for 401")
+	}
+
+	if _, err := newCalDAVSource("ftp://example.com/cal", "", "", ""); err == nil {
+		t.Error("newCalDAVSource() error = nil, want error for unsupported scheme")
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A config file holds settings under the names of the command-line flags,
+// with lists for repeatable flags. Settings at the top level apply to every
+// run, and those of the profile selected with -profile override them:
+//
+//	credentials: /home/me/.config/freecal/credentials.json
+//	tz: Asia/Tokyo
+//	profiles:
+//	  client-meetings:
+//	    calendar: [primary, client@example.com]
+//	    workstart: "10:00"
+//
+// Every setting can also be given as an environment variable named after the
+// flag, e.g. FREECAL_CALDAV_PASSWORD for -caldav-password. The precedence is
+// flags > environment > profile > top level of the file > defaults.
+
+// Settings that select the configuration itself and cannot be in the file.
+var configFlags = map[string]bool{"config": true, "profile": true}
+
+// secretFlags are masked when printing the effective configuration.
+var secretFlags = map[string]bool{"caldav-password": true, "caldav-token": true, "ms-client-secret": true}
+
+// configSettings maps flag names to their values.
+type configSettings map[string][]string
+
+type configFile struct {
+	settings configSettings
+	profiles map[string]configSettings
+}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/freecal/config.yaml, or
+// ~/.config/freecal/config.yaml.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "freecal", "config.yaml")
+}
+
+// loadConfigFile reads the config file at path. A missing file is only an
+// error when it was asked for explicitly.
+func loadConfigFile(path string, explicit bool) (*configFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return &configFile{}, nil
+		}
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+	defer f.Close()
+	cf, err := parseConfigFile(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cf, nil
+}
+
+func parseConfigFile(r io.Reader) (*configFile, error) {
+	var raw map[string]any
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid YAML: %w", err)
+	}
+
+	cf := &configFile{profiles: map[string]configSettings{}}
+	if profiles, ok := raw["profiles"]; ok {
+		m, ok := profiles.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("profiles must be a mapping of profile names to settings")
+		}
+		for name, p := range m {
+			pm, ok := p.(map[string]any)
+			if !ok && p != nil {
+				return nil, fmt.Errorf("profile %q must be a mapping of settings", name)
+			}
+			s, err := toConfigSettings(pm)
+			if err != nil {
+				return nil, fmt.Errorf("profile %q: %w", name, err)
+			}
+			cf.profiles[name] = s
+		}
+		delete(raw, "profiles")
+	}
+	s, err := toConfigSettings(raw)
+	if err != nil {
+		return nil, err
+	}
+	cf.settings = s
+	return cf, nil
+}
+
+func toConfigSettings(m map[string]any) (configSettings, error) {
+	s := configSettings{}
+	for key, v := range m {
+		switch v := v.(type) {
+		case []any:
+			for _, item := range v {
+				value, ok := scalarString(item)
+				if !ok {
+					return nil, fmt.Errorf("%s: list items must be plain values", key)
+				}
+				s[key] = append(s[key], value)
+			}
+		default:
+			value, ok := scalarString(v)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a plain value or a list", key)
+			}
+			s[key] = []string{value}
+		}
+	}
+	return s, nil
+}
+
+// scalarString returns a plain YAML value as the text of a flag value.
+// Unquoted dates such as "start: 2025-01-13" are decoded as times, and are
+// formatted back the way they were written; an empty value ("end:") is the
+// empty string.
+func scalarString(v any) (string, bool) {
+	switch v := v.(type) {
+	case nil:
+		return "", true
+	case string, bool, int, float64:
+		return fmt.Sprint(v), true
+	case time.Time:
+		if v.Location() == time.UTC && v.Equal(v.Truncate(24*time.Hour)) {
+			return v.Format("2006-01-02"), true
+		}
+		return v.Format(time.RFC3339), true
+	default:
+		return "", false
+	}
+}
+
+// envName returns the environment variable for a flag, e.g.
+// FREECAL_CALDAV_PASSWORD for caldav-password.
+func envName(flagName string) string {
+	return "FREECAL_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applyConfig sets every flag of fset that was not given on the command line
+// from the environment, the profile or the top level of the config file, in
+// this order. It returns where the value of each flag came from.
+func applyConfig(fset *flag.FlagSet, cf *configFile, profile string, getenv func(string) string) (map[string]string, error) {
+	var ps configSettings
+	if profile != "" {
+		var ok bool
+		if ps, ok = cf.profiles[profile]; !ok {
+			names := make([]string, 0, len(cf.profiles))
+			for n := range cf.profiles {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("unknown profile %q (have %s)", profile, strings.Join(names, ", "))
+		}
+	}
+	for _, s := range []configSettings{cf.settings, ps} {
+		for key := range s {
+			if fset.Lookup(key) == nil || configFlags[key] {
+				return nil, fmt.Errorf("unknown setting %q in config file", key)
+			}
+		}
+	}
+
+	sources := map[string]string{}
+	fset.Visit(func(f *flag.Flag) { sources[f.Name] = "flag" })
+
+	var err error
+	fset.VisitAll(func(f *flag.Flag) {
+		if err != nil || sources[f.Name] != "" || configFlags[f.Name] {
+			return
+		}
+		var values []string
+		var source string
+		switch {
+		case getenv(envName(f.Name)) != "":
+			values, source = []string{getenv(envName(f.Name))}, "env "+envName(f.Name)
+		case ps[f.Name] != nil:
+			values, source = ps[f.Name], "profile "+profile
+		case cf.settings[f.Name] != nil:
+			values, source = cf.settings[f.Name], "config file"
+		default:
+			sources[f.Name] = "default"
+			return
+		}
+		for _, v := range values {
+			if serr := fset.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("invalid %s from %s: %w", f.Name, source, serr)
+				return
+			}
+		}
+		sources[f.Name] = source
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+// writeEffectiveConfig prints the merged settings as a config file, with
+// where each value came from. Secrets are masked.
+func writeEffectiveConfig(w io.Writer, fset *flag.FlagSet, sources map[string]string) error {
+	var err error
+	fset.VisitAll(func(f *flag.Flag) {
+		if err != nil || configFlags[f.Name] {
+			return
+		}
+		var value string
+		switch {
+		case secretFlags[f.Name] && f.Value.String() != "":
+			value = "'********'"
+		case isListFlag(f.Value):
+			// flow style, to keep one line per setting
+			items := listFlagValues(f.Value)
+			quoted := make([]string, 0, len(items))
+			for _, item := range items {
+				quoted = append(quoted, strconv.Quote(item))
+			}
+			value = "[" + strings.Join(quoted, ", ") + "]"
+		default:
+			value = yamlScalar(f.Value)
+		}
+		_, err = fmt.Fprintf(w, "%s: %s  # %s\n", f.Name, value, sources[f.Name])
+	})
+	return err
+}
+
+func isListFlag(v flag.Value) bool {
+	return listFlagValues(v) != nil
+}
+
+// listFlagValues returns the values of a repeatable flag, or nil for other
+// flags.
+func listFlagValues(v flag.Value) []string {
+	switch l := v.(type) {
+	case *stringList:
+		return append([]string{}, *l...)
+	case *rawList:
+		return append([]string{}, *l...)
+	case *ruleFlag:
+		return l.values()
+	default:
+		return nil
+	}
+}
+
+// yamlScalar formats the value of a flag as a YAML scalar, keeping booleans
+// and numbers unquoted.
+func yamlScalar(v flag.Value) string {
+	var x any = v.String()
+	if g, ok := v.(flag.Getter); ok {
+		switch g.Get().(type) {
+		case bool, int:
+			x = g.Get()
+		}
+	}
+	b, err := yaml.Marshal(x)
+	if err != nil {
+		return strconv.Quote(v.String())
+	}
+	return strings.TrimSuffix(string(b), "\n")
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `
+credentials: /etc/freecal/credentials.json
+tz: Asia/Tokyo
+min: 30
+profiles:
+  client-meetings:
+    calendar: [primary, client@example.com]
+    workstart: "10:00"
+    min: 45
+    ignore:
+      - "summary=^Optional:, all hands"
+  empty:
+`
+
+type testFlags struct {
+	credentials, tz, workstart, password string
+	min                                  int
+	dryRun                               bool
+	calendars                            stringList
+	rules                                []ruleSpec
+}
+
+func newTestFlagSet() (*flag.FlagSet, *testFlags) {
+	f := &testFlags{}
+	fset := flag.NewFlagSet("freecal", flag.ContinueOnError)
+	fset.SetOutput(io.Discard)
+	fset.StringVar(&f.credentials, "credentials", "", "")
+	fset.StringVar(&f.tz, "tz", "UTC", "")
+	fset.StringVar(&f.workstart, "workstart", "09:00", "")
+	fset.StringVar(&f.password, "caldav-password", "", "")
+	fset.IntVar(&f.min, "min", 60, "")
+	fset.BoolVar(&f.dryRun, "dry-run", false, "")
+	fset.Var(&f.calendars, "calendar", "")
+	fset.Var(&ruleFlag{action: treatFree, specs: &f.rules}, "ignore", "")
+	fset.Var(&ruleFlag{action: treatSoft, specs: &f.rules}, "soft", "")
+	fset.String("profile", "", "")
+	return fset, f
+}
+
+func TestApplyConfigPrecedence(t *testing.T) {
+	cf, err := parseConfigFile(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatalf("parseConfigFile() error = %v", err)
+	}
+
+	fset, f := newTestFlagSet()
+	if err := fset.Parse([]string{"-tz", "Europe/London"}); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"FREECAL_TZ": "America/New_York", "FREECAL_MIN": "15", "FREECAL_CALDAV_PASSWORD": "secret"}
+	sources, err := applyConfig(fset, cf, "client-meetings", func(k string) string { return env[k] })
+	if err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+
+	if f.tz != "Europe/London" || sources["tz"] != "flag" {
+		t.Errorf("tz = %q from %q, want the flag", f.tz, sources["tz"])
+	}
+	if f.min != 15 || sources["min"] != "env FREECAL_MIN" {
+		t.Errorf("min = %d from %q, want the environment", f.min, sources["min"])
+	}
+	if f.workstart != "10:00" || sources["workstart"] != "profile client-meetings" {
+		t.Errorf("workstart = %q from %q, want the profile", f.workstart, sources["workstart"])
+	}
+	if f.credentials != "/etc/freecal/credentials.json" || sources["credentials"] != "config file" {
+		t.Errorf("credentials = %q from %q, want the config file", f.credentials, sources["credentials"])
+	}
+	if f.dryRun || sources["dry-run"] != "default" {
+		t.Errorf("dry-run = %v from %q, want the default", f.dryRun, sources["dry-run"])
+	}
+	if want := (stringList{"primary", "client@example.com"}); !reflect.DeepEqual(f.calendars, want) {
+		t.Errorf("calendar = %v, want %v", f.calendars, want)
+	}
+	if want := []ruleSpec{{action: treatFree, spec: "summary=^Optional:, all hands"}}; !reflect.DeepEqual(f.rules, want) {
+		t.Errorf("rules = %v, want %v", f.rules, want)
+	}
+
+	var buf bytes.Buffer
+	if err := writeEffectiveConfig(&buf, fset, sources); err != nil {
+		t.Fatalf("writeEffectiveConfig() error = %v", err)
+	}
+	want := `caldav-password: '********'  # env FREECAL_CALDAV_PASSWORD
+calendar: ["primary", "client@example.com"]  # profile client-meetings
+credentials: /etc/freecal/credentials.json  # config file
+dry-run: false  # default
+ignore: ["summary=^Optional:, all hands"]  # profile client-meetings
+min: 15  # env FREECAL_MIN
+soft: []  # default
+tz: Europe/London  # flag
+workstart: "10:00"  # profile client-meetings
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeEffectiveConfig() =\n%s\nwant\n%s", got, want)
+	}
+
+	// the printed configuration can be read back
+	printed, err := parseConfigFile(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("parseConfigFile(printed) error = %v", err)
+	}
+	if got := printed.settings["ignore"]; !reflect.DeepEqual(got, []string{"summary=^Optional:, all hands"}) {
+		t.Errorf("printed ignore = %v", got)
+	}
+}
+
+// A rule flag on the command line replaces the rules of that kind in the
+// config file, and leaves the other kind.
+func TestApplyConfigRules(t *testing.T) {
+	cf, err := parseConfigFile(strings.NewReader("ignore: [summary=^Optional:]\nsoft: [color=8]\n"))
+	if err != nil {
+		t.Fatalf("parseConfigFile() error = %v", err)
+	}
+	fset, f := newTestFlagSet()
+	if err := fset.Parse([]string{"-soft", "summary=(?i)office hours"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyConfig(fset, cf, "", func(string) string { return "" }); err != nil {
+		t.Fatalf("applyConfig() error = %v", err)
+	}
+	want := []ruleSpec{
+		{action: treatSoft, spec: "summary=(?i)office hours"},
+		{action: treatFree, spec: "summary=^Optional:"},
+	}
+	if !reflect.DeepEqual(f.rules, want) {
+		t.Errorf("rules = %v, want %v", f.rules, want)
+	}
+}
+
+func TestApplyConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		profile string
+	}{
+		{name: "unknown profile", file: testConfigFile, profile: "internal"},
+		{name: "unknown setting", file: "colour: blue\n"},
+		{name: "unknown setting in profile", file: "profiles:\n  a:\n    colour: blue\n", profile: "a"},
+		{name: "profile in file", file: "profile: a\n"},
+		{name: "invalid value", file: "min: many\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cf, err := parseConfigFile(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("parseConfigFile() error = %v", err)
+			}
+			fset, _ := newTestFlagSet()
+			if _, err := applyConfig(fset, cf, tt.profile, func(string) string { return "" }); err == nil {
+				t.Error("applyConfig() succeeded")
+			}
+		})
+	}
+
+	for _, file := range []string{"profiles: [a, b]\n", "tz: {name: Asia/Tokyo}\n", "tz: [\n"} {
+		if _, err := parseConfigFile(strings.NewReader(file)); err == nil {
+			t.Errorf("parseConfigFile(%q) succeeded", file)
+		}
+	}
+}
+
+func TestParseConfigFileScalars(t *testing.T) {
+	const file = "start: 2025-01-13\n" +
+		"end:\n" +
+		"since: 2025-01-13T09:00:00+09:00\n" +
+		"exclude: [12:00-13:00, 2025-01-15]\n" +
+		"min: 45\n"
+	cf, err := parseConfigFile(strings.NewReader(file))
+	if err != nil {
+		t.Fatalf("parseConfigFile() error = %v", err)
+	}
+	want := configSettings{
+		"start":   {"2025-01-13"},
+		"end":     {""},
+		"since":   {"2025-01-13T09:00:00+09:00"},
+		"exclude": {"12:00-13:00", "2025-01-15"},
+		"min":     {"45"},
+	}
+	if !reflect.DeepEqual(cf.settings, want) {
+		t.Errorf("settings = %v, want %v", cf.settings, want)
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.yaml")
+	if cf, err := loadConfigFile(missing, false); err != nil || len(cf.settings) != 0 {
+		t.Errorf("loadConfigFile(missing default) = %v, %v, want an empty config", cf, err)
+	}
+	if _, err := loadConfigFile(missing, true); err == nil {
+		t.Error("loadConfigFile(missing explicit) succeeded")
+	}
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cf, err := loadConfigFile(path, true)
+	if err != nil {
+		t.Fatalf("loadConfigFile() error = %v", err)
+	}
+	if _, ok := cf.profiles["empty"]; !ok {
+		t.Errorf("profiles = %v, want an empty profile", cf.profiles)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	if got, want := envName("caldav-password"), "FREECAL_CALDAV_PASSWORD"; got != want {
+		t.Errorf("envName() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// now is the clock for date resolution: it decides what today and +3d are,
+// and which working time is already over. It also stamps exported events
+// (DTSTAMP). Tests replace it to get stable results.
+var now = time.Now
+
+var (
+	relativeDatePattern = regexp.MustCompile(`^([+-]\d+)([dw])$`)
+	isoWeekPattern      = regexp.MustCompile(`(?i)^(\d{4})-W(\d{2})$`)
+)
+
+// parseDateSpec resolves a -start/-end value to the range of days it names,
+// as midnights in the location of today:
+//
+//   - 2025-01-13: that day
+//   - today, tomorrow, yesterday
+//   - +3d, +2w, -1d: days or weeks from today
+//   - this-week, next-week, last-week: Monday through Sunday
+//   - this-month, next-month, last-month
+//   - 2025-W03: an ISO week, Monday through Sunday
+//
+// For single days, first and last are the same day.
+func parseDateSpec(s string, today time.Time) (first, last time.Time, err error) {
+	loc := today.Location()
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	day := func(t time.Time) (time.Time, time.Time, error) { return t, t, nil }
+	week := func(monday time.Time) (time.Time, time.Time, error) { return monday, monday.AddDate(0, 0, 6), nil }
+	month := func(offset int) (time.Time, time.Time, error) {
+		first := time.Date(today.Year(), today.Month()+time.Month(offset), 1, 0, 0, 0, 0, loc)
+		return first, first.AddDate(0, 1, -1), nil
+	}
+	// days since Monday, as ISO weeks start on Monday
+	thisMonday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+
+	spec := strings.ToLower(strings.TrimSpace(s))
+	switch spec {
+	case "today":
+		return day(today)
+	case "tomorrow":
+		return day(today.AddDate(0, 0, 1))
+	case "yesterday":
+		return day(today.AddDate(0, 0, -1))
+	case "this-week":
+		return week(thisMonday)
+	case "next-week":
+		return week(thisMonday.AddDate(0, 0, 7))
+	case "last-week":
+		return week(thisMonday.AddDate(0, 0, -7))
+	case "this-month":
+		return month(0)
+	case "next-month":
+		return month(1)
+	case "last-month":
+		return month(-1)
+	}
+
+	if m := relativeDatePattern.FindStringSubmatch(spec); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if m[2] == "w" {
+			n *= 7
+		}
+		return day(today.AddDate(0, 0, n))
+	}
+	if m := isoWeekPattern.FindStringSubmatch(spec); m != nil {
+		year, _ := strconv.Atoi(m[1])
+		w, _ := strconv.Atoi(m[2])
+		// week 1 is the week with January 4th in it
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, loc)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday())+6)%7)+7*(w-1))
+		if y, got := monday.AddDate(0, 0, 3).ISOWeek(); w < 1 || y != year || got != w {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid ISO week %q", s)
+		}
+		return week(monday)
+	}
+	t, err := time.ParseInLocation("2006-01-02", spec, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q (want YYYY-MM-DD, today, tomorrow, +3d, +2w, next-week, this-month, 2025-W03, ...)", s)
+	}
+	return day(t)
+}
+
+// remainingIntervals drops the parts of in that are already over when the
+// search range [timeMin, timeMax) includes the current time, so that a
+// search run at 3 p.m. does not propose the morning of the same day. Ranges
+// entirely in the past or future are returned as they are.
+func remainingIntervals(in []interval, timeMin, timeMax time.Time) []interval {
+	t := now()
+	if t.Before(timeMin) || !t.Before(timeMax) {
+		return in
+	}
+	// from the next whole minute, not 15:04:05
+	t = alignToGrid(t, time.Minute, true)
+	var out []interval
+	for _, iv := range in {
+		if !iv.end.After(t) {
+			continue
+		}
+		if iv.start.Before(t) {
+			iv.start = t.In(iv.start.Location())
+		}
+		out = append(out, iv)
+	}
+	return out
+}
+
+// resolveDateRange returns the first and last day to search. -start defaults
+// to today; the range ends at -end, after -days days, or else at the end of
+// -start (so "-start next-week" alone searches the whole week).
+func resolveDateRange(startSpec, endSpec string, days int, today time.Time) (startDate, endDate time.Time, err error) {
+	if startSpec == "" {
+		startSpec = "today"
+	}
+	startDate, startLast, err := parseDateSpec(startSpec, today)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid -start: %w", err)
+	}
+	switch {
+	case endSpec != "" && days > 0:
+		return time.Time{}, time.Time{}, fmt.Errorf("-end and -days cannot be used together")
+	case endSpec != "":
+		if _, endDate, err = parseDateSpec(endSpec, today); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid -end: %w", err)
+		}
+	case days > 0:
+		endDate = startDate.AddDate(0, 0, days-1)
+	default:
+		endDate = startLast
+	}
+	if endDate.Before(startDate) {
+		return time.Time{}, time.Time{}, fmt.Errorf("-end is before -start")
+	}
+	return startDate, endDate, nil
+}
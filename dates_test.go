@@ -0,0 +1,166 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDateSpec(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	// Wednesday
+	today := time.Date(2025, 1, 15, 23, 30, 0, 0, loc)
+	d := func(y int, m time.Month, day int) time.Time { return time.Date(y, m, day, 0, 0, 0, 0, loc) }
+
+	tests := []struct {
+		spec      string
+		wantFirst time.Time
+		wantLast  time.Time
+		wantErr   bool
+	}{
+		{spec: "2025-01-20", wantFirst: d(2025, 1, 20), wantLast: d(2025, 1, 20)},
+		{spec: "today", wantFirst: d(2025, 1, 15), wantLast: d(2025, 1, 15)},
+		{spec: "Tomorrow", wantFirst: d(2025, 1, 16), wantLast: d(2025, 1, 16)},
+		{spec: "yesterday", wantFirst: d(2025, 1, 14), wantLast: d(2025, 1, 14)},
+		{spec: "+3d", wantFirst: d(2025, 1, 18), wantLast: d(2025, 1, 18)},
+		{spec: "+2w", wantFirst: d(2025, 1, 29), wantLast: d(2025, 1, 29)},
+		{spec: "-1d", wantFirst: d(2025, 1, 14), wantLast: d(2025, 1, 14)},
+		{spec: "this-week", wantFirst: d(2025, 1, 13), wantLast: d(2025, 1, 19)},
+		{spec: "next-week", wantFirst: d(2025, 1, 20), wantLast: d(2025, 1, 26)},
+		{spec: "last-week", wantFirst: d(2025, 1, 6), wantLast: d(2025, 1, 12)},
+		{spec: "this-month", wantFirst: d(2025, 1, 1), wantLast: d(2025, 1, 31)},
+		{spec: "next-month", wantFirst: d(2025, 2, 1), wantLast: d(2025, 2, 28)},
+		{spec: "last-month", wantFirst: d(2024, 12, 1), wantLast: d(2024, 12, 31)},
+		{spec: "2025-W03", wantFirst: d(2025, 1, 13), wantLast: d(2025, 1, 19)},
+		// week 1 of 2025 starts in 2024
+		{spec: "2025-w03", wantFirst: d(2025, 1, 13), wantLast: d(2025, 1, 19)},
+		{spec: " +3d ", wantFirst: d(2025, 1, 18), wantLast: d(2025, 1, 18)},
+		{spec: "+1W", wantFirst: d(2025, 1, 22), wantLast: d(2025, 1, 22)},
+		{spec: " 2025-01-20", wantFirst: d(2025, 1, 20), wantLast: d(2025, 1, 20)},
+		{spec: "2025-W01", wantFirst: d(2024, 12, 30), wantLast: d(2025, 1, 5)},
+		{spec: "2020-W53", wantFirst: d(2020, 12, 28), wantLast: d(2021, 1, 3)},
+		{spec: "2025-W53", wantErr: true},
+		{spec: "2025-W00", wantErr: true},
+		{spec: "2025-13-01", wantErr: true},
+		{spec: "3d", wantErr: true},
+		{spec: "someday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			first, last, err := parseDateSpec(tt.spec, today)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDateSpec(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !first.Equal(tt.wantFirst) || !last.Equal(tt.wantLast) {
+				t.Errorf("parseDateSpec(%q) = %s..%s, want %s..%s", tt.spec,
+					first.Format("2006-01-02"), last.Format("2006-01-02"),
+					tt.wantFirst.Format("2006-01-02"), tt.wantLast.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestResolveDateRange(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	// 2025-01-15 in Tokyo, while it is still the 14th in UTC
+	today := time.Date(2025, 1, 14, 16, 0, 0, 0, time.UTC).In(loc)
+	d := func(y int, m time.Month, day int) time.Time { return time.Date(y, m, day, 0, 0, 0, 0, loc) }
+
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		days      int
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{name: "defaults to today", wantStart: d(2025, 1, 15), wantEnd: d(2025, 1, 15)},
+		{name: "dates", start: "2025-01-13", end: "2025-01-17", wantStart: d(2025, 1, 13), wantEnd: d(2025, 1, 17)},
+		{name: "range start alone", start: "next-week", wantStart: d(2025, 1, 20), wantEnd: d(2025, 1, 26)},
+		{name: "range end", start: "today", end: "next-week", wantStart: d(2025, 1, 15), wantEnd: d(2025, 1, 26)},
+		{name: "relative end", start: "tomorrow", end: "+1w", wantStart: d(2025, 1, 16), wantEnd: d(2025, 1, 22)},
+		{name: "days", days: 5, wantStart: d(2025, 1, 15), wantEnd: d(2025, 1, 19)},
+		{name: "days from range", start: "next-week", days: 3, wantStart: d(2025, 1, 20), wantEnd: d(2025, 1, 22)},
+		{name: "end and days", end: "+3d", days: 3, wantErr: true},
+		{name: "end before start", start: "tomorrow", end: "today", wantErr: true},
+		{name: "invalid start", start: "soon", wantErr: true},
+		{name: "invalid end", end: "later", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := resolveDateRange(tt.start, tt.end, tt.days, today)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveDateRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("resolveDateRange() = %s..%s, want %s..%s",
+					start.Format("2006-01-02"), end.Format("2006-01-02"),
+					tt.wantStart.Format("2006-01-02"), tt.wantEnd.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestRemainingIntervals(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	defer func(orig func() time.Time) { now = orig }(now)
+	// 15:00:30 on Wednesday in Tokyo
+	now = func() time.Time { return time.Date(2025, 1, 15, 6, 0, 30, 0, time.UTC) }
+
+	at := func(day, h, m int) time.Time { return time.Date(2025, 1, day, h, m, 0, 0, loc) }
+	working := []interval{
+		{start: at(15, 9, 0), end: at(15, 12, 0)},
+		{start: at(15, 13, 0), end: at(15, 18, 0)},
+		{start: at(16, 9, 0), end: at(16, 18, 0)},
+	}
+
+	tests := []struct {
+		name             string
+		timeMin, timeMax time.Time
+		want             []interval
+	}{
+		{
+			name:    "range includes now",
+			timeMin: at(15, 0, 0),
+			timeMax: at(17, 0, 0),
+			want: []interval{
+				{start: at(15, 15, 1), end: at(15, 18, 0)},
+				{start: at(16, 9, 0), end: at(16, 18, 0)},
+			},
+		},
+		{
+			name:    "future range",
+			timeMin: at(16, 0, 0),
+			timeMax: at(17, 0, 0),
+			want:    working,
+		},
+		{
+			name:    "past range",
+			timeMin: at(13, 0, 0),
+			timeMax: at(15, 0, 0),
+			want:    working,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := remainingIntervals(working, tt.timeMin, tt.timeMax)
+			if len(got) != len(tt.want) {
+				t.Fatalf("remainingIntervals() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].start.Equal(tt.want[i].start) || !got[i].end.Equal(tt.want[i].end) {
+					t.Errorf("remainingIntervals()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+				if got[i].start.Location() != loc {
+					t.Errorf("remainingIntervals()[%d] is in %v, want %v", i, got[i].start.Location(), loc)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// zonedSlot is a slot converted into one of the -display-tz timezones.
+type zonedSlot struct {
+	zone  *time.Location
+	start time.Time
+	end   time.Time
+	// abbr and offset label the zone as of the slot, e.g. "PST" and
+	// "UTC-08:00", so they follow daylight saving time.
+	abbr   string
+	offset string
+	// dayShift is the number of days the slot's date in zone differs from
+	// the day it is listed under, e.g. -1 when it is still yesterday there.
+	dayShift int
+}
+
+func convertSlot(s interval, day time.Time, zone *time.Location) zonedSlot {
+	start, end := s.start.In(zone), s.end.In(zone)
+	abbr, offset := start.Zone()
+	return zonedSlot{
+		zone:     zone,
+		start:    start,
+		end:      end,
+		abbr:     abbr,
+		offset:   formatUTCOffset(offset),
+		dayShift: daysBetween(day, start),
+	}
+}
+
+// convertSlots converts the slots of a day into zone.
+func convertSlots(ss []interval, day time.Time, zone *time.Location) []zonedSlot {
+	out := make([]zonedSlot, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, convertSlot(s, day, zone))
+	}
+	return out
+}
+
+// formatUTCOffset formats an offset in seconds east of UTC as "UTC+09:00".
+func formatUTCOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("UTC%c%02d:%02d", sign, offset/3600, offset%3600/60)
+}
+
+// daysBetween returns the number of calendar days from the date of a to the
+// date of b, each in its own location.
+func daysBetween(a, b time.Time) int {
+	da := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	db := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da).Hours() / 24)
+}
+
+// zonedSlot formats a converted slot, with the day shift if any, e.g.
+// "17:00~18:00（-1日）".
+func (l *locale) zonedSlot(z zonedSlot) string {
+	s := l.slot(interval{start: z.start, end: z.end})
+	if z.dayShift != 0 {
+		s += fmt.Sprintf(l.dayShiftFormat, z.dayShift)
+	}
+	return s
+}
+
+// zonedSlots formats the slots of a day converted into one timezone, labeled
+// with the zone as of the first slot, e.g. "PST (UTC-08:00): 17:00~18:00（-1日）".
+func (l *locale) zonedSlots(zs []zonedSlot) string {
+	if len(zs) == 0 {
+		return ""
+	}
+	out := make([]string, 0, len(zs))
+	for _, z := range zs {
+		out = append(out, l.zonedSlot(z))
+	}
+	return fmt.Sprintf("%s (%s): %s", zs[0].abbr, zs[0].offset, strings.Join(out, l.listSep))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConvertSlot(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	kolkata, _ := time.LoadLocation("Asia/Kolkata")
+
+	tests := []struct {
+		name      string
+		start     time.Time
+		zone      *time.Location
+		want      string
+		wantAbbr  string
+		wantShift int
+	}{
+		{
+			name:      "previous day in Los Angeles",
+			start:     time.Date(2025, 1, 13, 10, 0, 0, 0, tokyo),
+			zone:      la,
+			want:      "17:00~18:00（-1日）",
+			wantAbbr:  "PST (UTC-08:00)",
+			wantShift: -1,
+		},
+		{
+			name:      "daylight saving time in summer",
+			start:     time.Date(2025, 7, 14, 10, 0, 0, 0, tokyo),
+			zone:      la,
+			want:      "18:00~19:00（-1日）",
+			wantAbbr:  "PDT (UTC-07:00)",
+			wantShift: -1,
+		},
+		{
+			name:     "same day in Berlin",
+			start:    time.Date(2025, 1, 13, 16, 0, 0, 0, tokyo),
+			zone:     berlin,
+			want:     "08:00~09:00",
+			wantAbbr: "CET (UTC+01:00)",
+		},
+		{
+			name:     "half-hour offset",
+			start:    time.Date(2025, 1, 13, 16, 0, 0, 0, tokyo),
+			zone:     kolkata,
+			want:     "12:30~13:30",
+			wantAbbr: "IST (UTC+05:30)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			day := time.Date(tt.start.Year(), tt.start.Month(), tt.start.Day(), 0, 0, 0, 0, tokyo)
+			z := convertSlot(interval{start: tt.start, end: tt.start.Add(time.Hour)}, day, tt.zone)
+			if got := localeJa.zonedSlot(z); got != tt.want {
+				t.Errorf("zonedSlot() = %q, want %q", got, tt.want)
+			}
+			if got := z.abbr + " (" + z.offset + ")"; got != tt.wantAbbr {
+				t.Errorf("zone label = %q, want %q", got, tt.wantAbbr)
+			}
+			if z.dayShift != tt.wantShift {
+				t.Errorf("dayShift = %d, want %d", z.dayShift, tt.wantShift)
+			}
+		})
+	}
+}
+
+func TestWriteDisplayTimezones(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	la, _ := time.LoadLocation("America/Los_Angeles")
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	opts := outputOptions{loc: loc, lang: &localeEn, displayZones: []*time.Location{la, berlin}}
+
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, formatMarkdown, testDays(loc), opts); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	want := "- Mon, Jan 13: 09:00–10:00, 14:00–15:30\n" +
+		"  - PST (UTC-08:00): 16:00–17:00 (-1d), 21:00–22:30 (-1d)\n" +
+		"  - CET (UTC+01:00): 01:00–02:00, 06:00–07:30\n" +
+		"- Tue, Jan 14: 10:30–12:00\n" +
+		"  - PST (UTC-08:00): 17:30–19:00 (-1d)\n" +
+		"  - CET (UTC+01:00): 02:30–04:00\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeOutput(markdown) =\n%s\nwant\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := writeOutput(&buf, formatJSON, testDays(loc)[1:], opts); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	wantJSON := `"display": [
+            {
+              "timezone": "America/Los_Angeles",
+              "abbreviation": "PST",
+              "utc_offset": "UTC-08:00",
+              "start": "2025-01-13T17:30:00-08:00",
+              "end": "2025-01-13T19:00:00-08:00"
+            },`
+	if got := buf.String(); !strings.Contains(got, wantJSON) {
+		t.Errorf("writeOutput(json) =\n%s\nwant to contain\n%s", got, wantJSON)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvFormatter writes a row per slot, for spreadsheets.
+type csvFormatter struct{}
+
+func (csvFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	cw := csv.NewWriter(w)
+	header := []string{"date", "weekday", "start", "end", "duration_minutes", "timezone"}
+	for _, zone := range opts.displayZones {
+		header = append(header, zone.String()+" start", zone.String()+" end")
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, d := range days {
+		for _, s := range d.slots {
+			start, end := s.start.In(opts.loc), s.end.In(opts.loc)
+			row := []string{
+				d.date.Format("2006-01-02"),
+				opts.lang.weekday(d.date),
+				start.Format("15:04"),
+				end.Format("15:04"),
+				strconv.Itoa(int(end.Sub(start).Minutes())),
+				opts.loc.String(),
+			}
+			for _, zone := range opts.displayZones {
+				// with the date and offset, as the day may differ
+				row = append(row, s.start.In(zone).Format(time.RFC3339), s.end.In(zone).Format(time.RFC3339))
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// tableFormatter writes an aligned table for the terminal, with a row per
+// slot. The date is only shown on the first row of each day.
+type tableFormatter struct{}
+
+func (tableFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	if len(days) == 0 {
+		_, err := fmt.Fprintln(w, opts.lang.noSlots)
+		return err
+	}
+	header := []string{"DATE", "DAY", "START", "END", "MINUTES"}
+	for _, zone := range opts.displayZones {
+		header = append(header, strings.ToUpper(zone.String()))
+	}
+	rows := [][]string{header}
+	for _, d := range days {
+		for i, s := range d.slots {
+			date, wd := "", ""
+			if i == 0 {
+				date, wd = d.date.Format("2006-01-02"), opts.lang.weekday(d.date)
+			}
+			start, end := s.start.In(opts.loc), s.end.In(opts.loc)
+			row := []string{
+				date, wd, start.Format("15:04"), end.Format("15:04"), strconv.Itoa(int(end.Sub(start).Minutes())),
+			}
+			for _, zone := range opts.displayZones {
+				z := convertSlot(s, d.date, zone)
+				row = append(row, z.abbr+" "+opts.lang.zonedSlot(z))
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], displayWidth(cell))
+		}
+	}
+	const minutesCol = 4
+	for _, row := range rows {
+		var b strings.Builder
+		for i, cell := range row {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			pad := strings.Repeat(" ", widths[i]-displayWidth(cell))
+			switch {
+			case i == minutesCol:
+				// right-align the numbers
+				b.WriteString(pad + cell)
+			case i == len(row)-1:
+				b.WriteString(cell)
+			default:
+				b.WriteString(cell + pad)
+			}
+		}
+		if _, err := fmt.Fprintln(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// displayWidth returns the number of terminal columns s takes, counting
+// East Asian wide characters such as 月 as two.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+			r >= 0x2E80 && r <= 0xA4CF, // CJK, Hiragana, Katakana, ...
+			r >= 0xAC00 && r <= 0xD7A3, // Hangul syllables
+			r >= 0xF900 && r <= 0xFAFF, // CJK compatibility ideographs
+			r >= 0xFF00 && r <= 0xFF60, // fullwidth forms
+			r >= 0xFFE0 && r <= 0xFFE6:
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// slackFormatter writes Slack mrkdwn, with the date of each day in bold.
+type slackFormatter struct{}
+
+func (slackFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	lang := opts.lang
+	if len(days) == 0 {
+		_, err := fmt.Fprintln(w, slackEscape(lang.noSlots))
+		return err
+	}
+	for _, d := range days {
+		line := "• *" + slackEscape(lang.day(d.date)) + "* " + slackEscape(lang.slots(d.slots))
+		if len(d.soft) > 0 {
+			line += slackEscape(fmt.Sprintf(lang.softFormat, lang.slots(d.soft)))
+		}
+		for _, zone := range opts.displayZones {
+			line += "\n    ◦ " + slackEscape(lang.zonedSlots(convertSlots(d.slots, d.date, zone)))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// slackEscape escapes the characters that have a meaning in Slack messages.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// htmlFormatter writes an HTML fragment to paste into emails.
+type htmlFormatter struct{}
+
+func (htmlFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	lang := opts.lang
+	if len(days) == 0 {
+		_, err := fmt.Fprintf(w, "<p>%s</p>\n", html.EscapeString(lang.noSlots))
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("<ul>\n")
+	for _, d := range days {
+		fmt.Fprintf(&b, "  <li><strong>%s</strong> %s", html.EscapeString(lang.day(d.date)), html.EscapeString(lang.slots(d.slots)))
+		if len(d.soft) > 0 {
+			fmt.Fprintf(&b, "<em>%s</em>", html.EscapeString(fmt.Sprintf(lang.softFormat, lang.slots(d.soft))))
+		}
+		if len(opts.displayZones) > 0 {
+			b.WriteString("\n    <ul>\n")
+			for _, zone := range opts.displayZones {
+				fmt.Fprintf(&b, "      <li>%s</li>\n", html.EscapeString(lang.zonedSlots(convertSlots(d.slots, d.date, zone))))
+			}
+			b.WriteString("    </ul>\n  ")
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
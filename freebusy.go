@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+// Limits of the FreeBusy API. A single request accepts at most 50 calendars
+// and rejects time ranges longer than about two months (timeRangeTooLong),
+// so larger queries are split into several requests.
+const (
+	freeBusyMaxCalendars = 50
+	freeBusyMaxRange     = 60 * 24 * time.Hour
+)
+
+// freeBusySource returns the busy intervals of Google calendars using the
+// FreeBusy API. It only needs free/busy access, which is all we get for most
+// colleagues' and external calendars.
+type freeBusySource struct {
+	svc         *calendar.Service
+	calendarIDs []string
+}
+
+func (s *freeBusySource) name() string {
+	return strings.Join(s.calendarIDs, ",")
+}
+
+func (s *freeBusySource) fetchBusy(
+	ctx context.Context,
+	startDate, endDate time.Time,
+	loc *time.Location,
+) ([]interval, error) {
+	timeMin, timeMax := queryRange(startDate, endDate, loc)
+
+	var busyAll []interval
+	for _, ids := range chunkStrings(s.calendarIDs, freeBusyMaxCalendars) {
+		for _, r := range splitRange(timeMin, timeMax, freeBusyMaxRange) {
+			req := &calendar.FreeBusyRequest{
+				TimeMin:  r.start.Format(time.RFC3339),
+				TimeMax:  r.end.Format(time.RFC3339),
+				TimeZone: loc.String(),
+			}
+			for _, id := range ids {
+				req.Items = append(req.Items, &calendar.FreeBusyRequestItem{Id: id})
+			}
+			resp, err := s.svc.Freebusy.Query(req).Context(ctx).Do()
+			if err != nil {
+				return nil, err
+			}
+			busy, err := freeBusyToIntervals(resp, loc)
+			if err != nil {
+				return nil, err
+			}
+			busyAll = append(busyAll, busy...)
+		}
+	}
+	return busyAll, nil
+}
+
+func freeBusyToIntervals(resp *calendar.FreeBusyResponse, loc *time.Location) ([]interval, error) {
+	var busyAll []interval
+	for id, cal := range resp.Calendars {
+		if len(cal.Errors) > 0 {
+			reasons := make([]string, 0, len(cal.Errors))
+			for _, e := range cal.Errors {
+				reasons = append(reasons, e.Reason)
+			}
+			return nil, fmt.Errorf("freebusy %s: %s", id, strings.Join(reasons, ", "))
+		}
+		for _, p := range cal.Busy {
+			s, err1 := time.Parse(time.RFC3339, p.Start)
+			e, err2 := time.Parse(time.RFC3339, p.End)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			if !e.After(s) {
+				continue
+			}
+			busyAll = append(busyAll, interval{start: s.In(loc), end: e.In(loc)})
+		}
+	}
+	return busyAll, nil
+}
+
+// isNoEventAccess reports whether err means the events of a calendar cannot
+// be read, in which case its free/busy information may still be available.
+// Other 403 errors, such as exceeded rate limits, are not about access and
+// must not silently downgrade the calendar to free/busy.
+func isNoEventAccess(err error) bool {
+	var gerr *googleapi.Error
+	if !errors.As(err, &gerr) {
+		return false
+	}
+	switch gerr.Code {
+	case http.StatusNotFound:
+		return true
+	case http.StatusForbidden:
+		if len(gerr.Errors) == 0 {
+			return true
+		}
+		for _, e := range gerr.Errors {
+			if !noEventAccessReasons[e.Reason] {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// noEventAccessReasons are the error reasons of the Calendar API that mean
+// the caller may not read the events of a calendar.
+var noEventAccessReasons = map[string]bool{
+	"forbidden":               true,
+	"notFound":                true,
+	"insufficientPermissions": true,
+}
+
+func chunkStrings(in []string, size int) [][]string {
+	var out [][]string
+	for len(in) > size {
+		out = append(out, in[:size])
+		in = in[size:]
+	}
+	if len(in) > 0 {
+		out = append(out, in)
+	}
+	return out
+}
+
+func splitRange(start, end time.Time, maxLen time.Duration) []interval {
+	var out []interval
+	for start.Before(end) {
+		next := start.Add(maxLen)
+		if next.After(end) {
+			next = end
+		}
+		out = append(out, interval{start: start, end: next})
+		start = next
+	}
+	return out
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+func TestChunkStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []string
+		size int
+		want [][]string
+	}{
+		{
+			name: "empty",
+			in:   nil,
+			size: 2,
+			want: nil,
+		},
+		{
+			name: "fits in one chunk",
+			in:   []string{"a", "b"},
+			size: 2,
+			want: [][]string{{"a", "b"}},
+		},
+		{
+			name: "split with remainder",
+			in:   []string{"a", "b", "c", "d", "e"},
+			size: 2,
+			want: [][]string{{"a", "b"}, {"c", "d"}, {"e"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkStrings(tt.in, tt.size)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkStrings() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitRange(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+
+	tests := []struct {
+		name string
+		end  time.Time
+		want []interval
+	}{
+		{
+			name: "shorter than limit",
+			end:  start.AddDate(0, 0, 10),
+			want: []interval{{start: start, end: start.AddDate(0, 0, 10)}},
+		},
+		{
+			name: "longer than limit",
+			end:  start.AddDate(0, 0, 130),
+			want: []interval{
+				{start: start, end: start.AddDate(0, 0, 60)},
+				{start: start.AddDate(0, 0, 60), end: start.AddDate(0, 0, 120)},
+				{start: start.AddDate(0, 0, 120), end: start.AddDate(0, 0, 130)},
+			},
+		},
+		{
+			name: "empty range",
+			end:  start,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitRange(start, tt.end, freeBusyMaxRange)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitRange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeBusyToIntervals(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	t.Run("busy periods", func(t *testing.T) {
+		resp := &calendar.FreeBusyResponse{
+			Calendars: map[string]calendar.FreeBusyCalendar{
+				"alice@example.com": {
+					Busy: []*calendar.TimePeriod{
+						{Start: "2025-01-13T01:00:00Z", End: "2025-01-13T02:00:00Z"},
+						{Start: "invalid", End: "2025-01-13T02:00:00Z"},
+					},
+				},
+			},
+		}
+		got, err := freeBusyToIntervals(resp, loc)
+		if err != nil {
+			t.Fatalf("freeBusyToIntervals() error = %v", err)
+		}
+		want := []interval{{
+			start: time.Date(2025, 1, 13, 10, 0, 0, 0, loc),
+			end:   time.Date(2025, 1, 13, 11, 0, 0, 0, loc),
+		}}
+		if len(got) != len(want) || !got[0].start.Equal(want[0].start) || !got[0].end.Equal(want[0].end) {
+			t.Errorf("freeBusyToIntervals() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("calendar error", func(t *testing.T) {
+		resp := &calendar.FreeBusyResponse{
+			Calendars: map[string]calendar.FreeBusyCalendar{
+				"nobody@example.com": {
+					Errors: []*calendar.Error{{Domain: "global", Reason: "notFound"}},
+				},
+			},
+		}
+		if _, err := freeBusyToIntervals(resp, loc); err == nil {
+			t.Error("freeBusyToIntervals() error = nil, want error")
+		}
+	})
+}
+
+func TestIsNoEventAccess(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"forbidden", &googleapi.Error{Code: http.StatusForbidden}, true},
+		{"not found", &googleapi.Error{Code: http.StatusNotFound}, true},
+		{"wrapped", fmt.Errorf("list: %w", &googleapi.Error{Code: http.StatusNotFound}), true},
+		{"forbidden with reason", &googleapi.Error{
+			Code:   http.StatusForbidden,
+			Errors: []googleapi.ErrorItem{{Reason: "forbidden"}},
+		}, true},
+		{"rate limit", &googleapi.Error{
+			Code:   http.StatusForbidden,
+			Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}},
+		}, false},
+		{"usage limit", &googleapi.Error{
+			Code:   http.StatusForbidden,
+			Errors: []googleapi.ErrorItem{{Reason: "quotaExceeded", Message: "Calendar usage limits exceeded."}},
+		}, false},
+		{"server error", &googleapi.Error{Code: http.StatusInternalServerError}, false},
+		{"other error", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNoEventAccess(tt.err); got != tt.want {
+				t.Errorf("isNoEventAccess() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ toolchain go1.23.12
 require (
 	golang.org/x/oauth2 v0.30.0
 	google.golang.org/api v0.246.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
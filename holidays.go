@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// holidayFunc reports whether a day is a public holiday, and its name.
+type holidayFunc func(day time.Time) (name string, ok bool)
+
+var holidayCalendars = map[string]holidayFunc{
+	"jp":   jpHoliday,
+	"none": func(time.Time) (string, bool) { return "", false },
+}
+
+func holidayCalendarNames() string {
+	names := make([]string, 0, len(holidayCalendars))
+	for n := range holidayCalendars {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+func lookupHolidayCalendar(name string) (holidayFunc, error) {
+	f, ok := holidayCalendars[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown holiday calendar %q (want %s)", name, holidayCalendarNames())
+	}
+	return f, nil
+}
+
+// jpHoliday reports whether day is a Japanese national holiday (国民の祝日),
+// a substitute holiday (振替休日) or a citizens' holiday (国民の休日).
+// Equinox days are calculated for 1980-2099.
+func jpHoliday(day time.Time) (string, bool) {
+	name, ok := jpHolidaysInYear(day.Year())[day.Format("01-02")]
+	return name, ok
+}
+
+// jpHolidaysInYear returns the holidays of year keyed by "MM-DD".
+func jpHolidaysInYear(y int) map[string]string {
+	h := map[string]string{}
+	add := func(m time.Month, d int, name string) {
+		h[fmt.Sprintf("%02d-%02d", int(m), d)] = name
+	}
+	nthMonday := func(m time.Month, n int) int {
+		first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).Weekday()
+		return 1 + (int(time.Monday)-int(first)+7)%7 + 7*(n-1)
+	}
+
+	add(time.January, 1, "元日")
+	if y >= 2000 {
+		add(time.January, nthMonday(time.January, 2), "成人の日")
+	} else {
+		add(time.January, 15, "成人の日")
+	}
+	if y >= 1967 {
+		add(time.February, 11, "建国記念の日")
+	}
+	if y >= 2020 {
+		add(time.February, 23, "天皇誕生日")
+	}
+	if d, ok := vernalEquinoxDay(y); ok {
+		add(time.March, d, "春分の日")
+	}
+	switch {
+	case y >= 2007:
+		add(time.April, 29, "昭和の日")
+	case y >= 1989:
+		add(time.April, 29, "みどりの日")
+	default:
+		add(time.April, 29, "天皇誕生日")
+	}
+	add(time.May, 3, "憲法記念日")
+	if y >= 2007 {
+		add(time.May, 4, "みどりの日")
+	}
+	add(time.May, 5, "こどもの日")
+	switch {
+	case y == 2020:
+		add(time.July, 23, "海の日")
+	case y == 2021:
+		add(time.July, 22, "海の日")
+	case y >= 2003:
+		add(time.July, nthMonday(time.July, 3), "海の日")
+	case y >= 1996:
+		add(time.July, 20, "海の日")
+	}
+	switch {
+	case y == 2020:
+		add(time.August, 10, "山の日")
+	case y == 2021:
+		add(time.August, 8, "山の日")
+	case y >= 2016:
+		add(time.August, 11, "山の日")
+	}
+	switch {
+	case y >= 2003:
+		add(time.September, nthMonday(time.September, 3), "敬老の日")
+	case y >= 1966:
+		add(time.September, 15, "敬老の日")
+	}
+	if d, ok := autumnalEquinoxDay(y); ok {
+		add(time.September, d, "秋分の日")
+	}
+	switch {
+	case y == 2020:
+		add(time.July, 24, "スポーツの日")
+	case y == 2021:
+		add(time.July, 23, "スポーツの日")
+	case y >= 2022:
+		add(time.October, nthMonday(time.October, 2), "スポーツの日")
+	case y >= 2000:
+		add(time.October, nthMonday(time.October, 2), "体育の日")
+	case y >= 1966:
+		add(time.October, 10, "体育の日")
+	}
+	add(time.November, 3, "文化の日")
+	add(time.November, 23, "勤労感謝の日")
+	if y >= 1989 && y <= 2018 {
+		add(time.December, 23, "天皇誕生日")
+	}
+
+	// one-off holidays
+	switch y {
+	case 1989:
+		add(time.February, 24, "昭和天皇の大喪の礼")
+	case 1990:
+		add(time.November, 12, "即位礼正殿の儀")
+	case 1993:
+		add(time.June, 9, "皇太子徳仁親王の結婚の儀")
+	case 2019:
+		add(time.May, 1, "天皇の即位の日")
+		add(time.October, 22, "即位礼正殿の儀")
+	}
+
+	// 国民の休日: a day between two national holidays (since 1985)
+	if y >= 1986 {
+		for d := time.Date(y, 1, 2, 0, 0, 0, 0, time.UTC); d.Year() == y; d = d.AddDate(0, 0, 1) {
+			key := d.Format("01-02")
+			_, today := h[key]
+			_, prev := h[d.AddDate(0, 0, -1).Format("01-02")]
+			_, next := h[d.AddDate(0, 0, 1).Format("01-02")]
+			if !today && prev && next && d.AddDate(0, 0, 1).Year() == y {
+				h[key] = "国民の休日"
+			}
+		}
+	}
+
+	// 振替休日: a holiday on Sunday moves to the next non-holiday (since 1973)
+	if y >= 1973 {
+		var sundays []time.Time
+		for key := range h {
+			d, _ := time.Parse("2006-01-02", fmt.Sprintf("%d-%s", y, key))
+			if d.Weekday() == time.Sunday {
+				sundays = append(sundays, d)
+			}
+		}
+		for _, d := range sundays {
+			for d = d.AddDate(0, 0, 1); ; d = d.AddDate(0, 0, 1) {
+				if _, ok := h[d.Format("01-02")]; !ok {
+					break
+				}
+			}
+			if d.Year() == y {
+				h[d.Format("01-02")] = "振替休日"
+			}
+		}
+	}
+	return h
+}
+
+// vernalEquinoxDay and autumnalEquinoxDay use the usual approximation of the
+// equinox dates published by the National Astronomical Observatory of Japan.
+func vernalEquinoxDay(y int) (int, bool) {
+	return equinoxDay(y, 20.8431)
+}
+
+func autumnalEquinoxDay(y int) (int, bool) {
+	return equinoxDay(y, 23.2488)
+}
+
+func equinoxDay(y int, base float64) (int, bool) {
+	if y < 1980 || y > 2099 {
+		return 0, false
+	}
+	n := float64(y - 1980)
+	return int(math.Floor(base + 0.242194*n - math.Floor(n/4))), true
+}
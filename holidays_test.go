@@ -0,0 +1,101 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJPHoliday(t *testing.T) {
+	tests := []struct {
+		date string
+		want string
+	}{
+		{"2025-01-01", "元日"},
+		{"2025-01-13", "成人の日"},
+		{"2025-02-11", "建国記念の日"},
+		{"2025-02-23", "天皇誕生日"},
+		{"2025-02-24", "振替休日"},
+		{"2025-03-20", "春分の日"},
+		{"2025-04-29", "昭和の日"},
+		{"2025-05-03", "憲法記念日"},
+		{"2025-05-04", "みどりの日"},
+		{"2025-05-05", "こどもの日"},
+		{"2025-05-06", "振替休日"},
+		{"2025-07-21", "海の日"},
+		{"2025-08-11", "山の日"},
+		{"2025-09-15", "敬老の日"},
+		{"2025-09-23", "秋分の日"},
+		{"2025-10-13", "スポーツの日"},
+		{"2025-11-03", "文化の日"},
+		{"2025-11-23", "勤労感謝の日"},
+		{"2025-11-24", "振替休日"},
+		{"2026-09-22", "国民の休日"},
+		{"2015-09-22", "国民の休日"},
+		{"2019-04-30", "国民の休日"},
+		{"2019-05-01", "天皇の即位の日"},
+		{"2019-05-02", "国民の休日"},
+		{"2019-10-22", "即位礼正殿の儀"},
+		{"2018-12-23", "天皇誕生日"},
+		{"2018-12-24", "振替休日"},
+		{"2020-07-24", "スポーツの日"},
+		{"2021-08-09", "振替休日"},
+		{"2009-05-06", "振替休日"},
+		{"2024-03-20", "春分の日"},
+		{"2024-09-22", "秋分の日"},
+		{"2024-09-23", "振替休日"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			day, _ := time.Parse("2006-01-02", tt.date)
+			got, ok := jpHoliday(day)
+			if !ok || got != tt.want {
+				t.Errorf("jpHoliday(%s) = (%q, %v), want (%q, true)", tt.date, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestJPHolidayWorkdays(t *testing.T) {
+	for _, date := range []string{
+		"2025-01-14",
+		"2025-05-07",
+		"2019-12-23", // no Emperor's Birthday in 2019
+		"2020-10-12", // Sports Day moved to July in 2020
+		"2025-12-29",
+	} {
+		day, _ := time.Parse("2006-01-02", date)
+		if name, ok := jpHoliday(day); ok {
+			t.Errorf("jpHoliday(%s) = (%q, true), want not a holiday", date, name)
+		}
+	}
+}
+
+func TestJPHolidayCount(t *testing.T) {
+	// number of holidays per year as published by the Cabinet Office
+	tests := map[int]int{
+		2019: 22,
+		2020: 18,
+		2024: 21,
+		2025: 19,
+	}
+	for year, want := range tests {
+		if got := len(jpHolidaysInYear(year)); got != want {
+			t.Errorf("len(jpHolidaysInYear(%d)) = %d, want %d", year, got, want)
+		}
+	}
+}
+
+func TestLookupHolidayCalendar(t *testing.T) {
+	day, _ := time.Parse("2006-01-02", "2025-01-01")
+	none, err := lookupHolidayCalendar("none")
+	if err != nil {
+		t.Fatalf("lookupHolidayCalendar(none) error = %v", err)
+	}
+	if _, ok := none(day); ok {
+		t.Error("none calendar reports a holiday")
+	}
+	if _, err := lookupHolidayCalendar("xx"); err == nil {
+		t.Error("lookupHolidayCalendar(xx) error = nil, want error")
+	}
+}
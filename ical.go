@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// icalProp is a single iCalendar content line (RFC 5545 section 3.1).
+type icalProp struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// icalComponent is a BEGIN/END block such as VEVENT or VTIMEZONE.
+type icalComponent struct {
+	name       string
+	props      []icalProp
+	components []*icalComponent
+}
+
+func (c *icalComponent) prop(name string) (icalProp, bool) {
+	for _, p := range c.props {
+		if p.name == name {
+			return p, true
+		}
+	}
+	return icalProp{}, false
+}
+
+func (c *icalComponent) value(name string) string {
+	p, _ := c.prop(name)
+	return p.value
+}
+
+func (c *icalComponent) all(name string) []icalProp {
+	var out []icalProp
+	for _, p := range c.props {
+		if p.name == name {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parseICal parses an iCalendar stream and returns its top-level components
+// (usually a single VCALENDAR).
+func parseICal(r io.Reader) ([]*icalComponent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var roots []*icalComponent
+	var stack []*icalComponent
+	for i, l := range lines {
+		p, err := parseICalLine(l)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		switch p.name {
+		case "BEGIN":
+			c := &icalComponent{name: strings.ToUpper(p.value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.components = append(parent.components, c)
+			} else {
+				roots = append(roots, c)
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != strings.ToUpper(p.value) {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", i+1, p.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property %s outside of a component", i+1, p.name)
+			}
+			c := stack[len(stack)-1]
+			c.props = append(c.props, p)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing END:%s", stack[len(stack)-1].name)
+	}
+	return roots, nil
+}
+
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		l := strings.TrimRight(sc.Text(), "\r")
+		if l == "" {
+			continue
+		}
+		if (l[0] == ' ' || l[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, sc.Err()
+}
+
+func parseICalLine(l string) (icalProp, error) {
+	// find the ':' that separates name and parameters from the value,
+	// skipping any ':' inside quoted parameter values
+	inQuote := false
+	sep := -1
+	for i, r := range l {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ':' && !inQuote {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return icalProp{}, fmt.Errorf("invalid content line %q", l)
+	}
+
+	p := icalProp{value: l[sep+1:]}
+	parts := splitICalParams(l[:sep])
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		if p.params == nil {
+			p.params = map[string]string{}
+		}
+		p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+	}
+	return p, nil
+}
+
+func splitICalParams(s string) []string {
+	var out []string
+	inQuote := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case r == ';' && !inQuote:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
+
+func icalUnescapeText(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
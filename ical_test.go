@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseICalLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		want    icalProp
+		wantErr bool
+	}{
+		{
+			name: "simple",
+			line: "SUMMARY:Design review",
+			want: icalProp{name: "SUMMARY", value: "Design review"},
+		},
+		{
+			name: "parameters",
+			line: "dtstart;TZID=Asia/Tokyo;VALUE=DATE-TIME:20250113T090000",
+			want: icalProp{
+				name:   "DTSTART",
+				params: map[string]string{"TZID": "Asia/Tokyo", "VALUE": "DATE-TIME"},
+				value:  "20250113T090000",
+			},
+		},
+		{
+			name: "quoted parameter with colon and semicolon",
+			line: `ATTENDEE;CN="Doe; John";DELEGATED-FROM="mailto:a@example.com":mailto:john@example.com`,
+			want: icalProp{
+				name:   "ATTENDEE",
+				params: map[string]string{"CN": "Doe; John", "DELEGATED-FROM": "mailto:a@example.com"},
+				value:  "mailto:john@example.com",
+			},
+		},
+		{
+			name:    "missing value",
+			line:    "SUMMARY",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseICalLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseICalLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseICalLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseICal(t *testing.T) {
+	src := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"SUMMARY:A very long\r\n" +
+		"  summary\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	roots, err := parseICal(strings.NewReader(src))
+	if err != nil {
+		t.Fatalf("parseICal() error = %v", err)
+	}
+	if len(roots) != 1 || roots[0].name != "VCALENDAR" || len(roots[0].components) != 1 {
+		t.Fatalf("parseICal() = %+v, want one VCALENDAR with one component", roots)
+	}
+	if got, want := roots[0].components[0].value("SUMMARY"), "A very long summary"; got != want {
+		t.Errorf("SUMMARY = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{
+		"BEGIN:VCALENDAR\nEND:VEVENT\n",
+		"BEGIN:VCALENDAR\n",
+		"SUMMARY:orphan\n",
+	} {
+		if _, err := parseICal(strings.NewReader(bad)); err == nil {
+			t.Errorf("parseICal(%q) error = nil, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	icsDateTimeFormat    = "20060102T150405"
+	icsUTCDateTimeFormat = "20060102T150405Z"
+	icsMaxLineOctets     = 75
+)
+
+// icsWriter writes iCalendar content lines with CRLF line endings, folding
+// lines longer than 75 octets as required by RFC 5545. The first write error
+// is kept in err and all later writes are skipped.
+type icsWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (iw *icsWriter) line(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	l := name + ":" + value
+	var b strings.Builder
+	limit := icsMaxLineOctets
+	for len(l) > limit {
+		// do not split inside a multi-byte UTF-8 sequence
+		n := limit
+		for n > 0 && l[n]&0xC0 == 0x80 {
+			n--
+		}
+		b.WriteString(l[:n])
+		b.WriteString("\r\n ")
+		l = l[n:]
+		limit = icsMaxLineOctets - 1 // continuation lines start with a space
+	}
+	b.WriteString(l)
+	b.WriteString("\r\n")
+	_, iw.err = io.WriteString(iw.w, b.String())
+}
+
+func icsEscapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+func icsOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// icsSlotUID derives the UID from the slot itself, so exporting the same slot
+// twice updates the existing hold instead of creating a duplicate.
+func icsSlotUID(s interval) string {
+	return fmt.Sprintf("%s-%s@freecal",
+		s.start.UTC().Format(icsUTCDateTimeFormat), s.end.UTC().Format(icsUTCDateTimeFormat))
+}
+
+// icsFormatter writes every slot as a tentative, transparent VEVENT, to
+// import as holds into a calendar.
+type icsFormatter struct{}
+
+func (icsFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	loc := opts.loc
+	iw := &icsWriter{w: w}
+	iw.line("BEGIN", "VCALENDAR")
+	iw.line("VERSION", "2.0")
+	iw.line("PRODID", "-//ngs//freecal//EN")
+	iw.line("CALSCALE", "GREGORIAN")
+	iw.line("METHOD", "PUBLISH")
+
+	if len(days) > 0 {
+		first := days[0].slots[0].start
+		lastDay := days[len(days)-1]
+		last := lastDay.slots[len(lastDay.slots)-1].end
+		writeVTimezone(iw, loc, first, last)
+	}
+
+	stamp := now().UTC().Format(icsUTCDateTimeFormat)
+	tzid := loc.String()
+	for _, d := range days {
+		for _, s := range d.slots {
+			iw.line("BEGIN", "VEVENT")
+			iw.line("UID", icsSlotUID(s))
+			iw.line("DTSTAMP", stamp)
+			iw.line("DTSTART;TZID="+tzid, s.start.In(loc).Format(icsDateTimeFormat))
+			iw.line("DTEND;TZID="+tzid, s.end.In(loc).Format(icsDateTimeFormat))
+			iw.line("SUMMARY", icsEscapeText("Free slot"))
+			iw.line("STATUS", "TENTATIVE")
+			iw.line("TRANSP", "TRANSPARENT")
+			iw.line("END", "VEVENT")
+		}
+	}
+
+	iw.line("END", "VCALENDAR")
+	return iw.err
+}
+
+// writeVTimezone describes loc between from and to. Instead of recurrence
+// rules, every transition in the range is written as its own observance,
+// which is exact for any zone the Go time package knows about.
+func writeVTimezone(iw *icsWriter, loc *time.Location, from, to time.Time) {
+	iw.line("BEGIN", "VTIMEZONE")
+	iw.line("TZID", loc.String())
+
+	t := from.In(loc)
+	for {
+		name, offset := t.Zone()
+		start, end := t.ZoneBounds()
+
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+		fromOffset := offset
+		dtstart := "19700101T000000"
+		if !start.IsZero() {
+			_, fromOffset = start.Add(-time.Second).Zone()
+			dtstart = start.In(time.FixedZone("", fromOffset)).Format(icsDateTimeFormat)
+		}
+
+		iw.line("BEGIN", kind)
+		iw.line("DTSTART", dtstart)
+		iw.line("TZOFFSETFROM", icsOffset(fromOffset))
+		iw.line("TZOFFSETTO", icsOffset(offset))
+		if name != "" {
+			iw.line("TZNAME", icsEscapeText(name))
+		}
+		iw.line("END", kind)
+
+		if end.IsZero() || !end.Before(to) {
+			break
+		}
+		t = end
+	}
+
+	iw.line("END", "VTIMEZONE")
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICS(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC) }
+
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, formatICS, testDays(loc)[1:], outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	want := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//ngs//freecal//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"BEGIN:VTIMEZONE",
+		"TZID:Asia/Tokyo",
+		"BEGIN:STANDARD",
+		"DTSTART:19510909T010000",
+		"TZOFFSETFROM:+1000",
+		"TZOFFSETTO:+0900",
+		"TZNAME:JST",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:20250114T013000Z-20250114T030000Z@freecal",
+		"DTSTAMP:20250110T000000Z",
+		"DTSTART;TZID=Asia/Tokyo:20250114T103000",
+		"DTEND;TZID=Asia/Tokyo:20250114T120000",
+		"SUMMARY:Free slot",
+		"STATUS:TENTATIVE",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	if got := buf.String(); got != want {
+		t.Errorf("writeOutput() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteVTimezoneTransitions(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	from := time.Date(2025, 3, 3, 9, 0, 0, 0, loc)
+	to := time.Date(2025, 3, 14, 17, 0, 0, 0, loc)
+
+	var buf bytes.Buffer
+	iw := &icsWriter{w: &buf}
+	writeVTimezone(iw, loc, from, to)
+	if iw.err != nil {
+		t.Fatalf("writeVTimezone() error = %v", iw.err)
+	}
+	got := buf.String()
+	for _, want := range []string{
+		"BEGIN:STANDARD\r\nDTSTART:20241103T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nTZNAME:EST\r\nEND:STANDARD\r\n",
+		"BEGIN:DAYLIGHT\r\nDTSTART:20250309T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\nEND:DAYLIGHT\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("writeVTimezone() =\n%s\nwant to contain\n%s", got, want)
+		}
+	}
+}
+
+func TestICSWriterFolding(t *testing.T) {
+	tests := []struct {
+		name      string
+		value     string
+		wantLines int
+	}{
+		{name: "short", value: "Free", wantLines: 1},
+		{name: "multi-byte", value: strings.Repeat("あ", 40), wantLines: 2},
+		// 75 octets, then exactly 75 left, which no longer fit after the space
+		{name: "75 left after a fold", value: strings.Repeat("x", 150-len("SUMMARY:")), wantLines: 3},
+		{name: "folded twice", value: strings.Repeat("x", 200), wantLines: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			iw := &icsWriter{w: &buf}
+			iw.line("SUMMARY", tt.value)
+			if iw.err != nil {
+				t.Fatalf("line() error = %v", iw.err)
+			}
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+			if len(lines) != tt.wantLines {
+				t.Errorf("line() wrote %d lines, want %d: %q", len(lines), tt.wantLines, buf.String())
+			}
+			var joined strings.Builder
+			for i, l := range lines {
+				if len(l) > icsMaxLineOctets {
+					t.Errorf("line %d has %d octets, want <= %d", i, len(l), icsMaxLineOctets)
+				}
+				if i > 0 {
+					l = strings.TrimPrefix(l, " ")
+				}
+				joined.WriteString(l)
+			}
+			if want := "SUMMARY:" + tt.value; joined.String() != want {
+				t.Errorf("unfolded = %q, want %q", joined.String(), want)
+			}
+		})
+	}
+}
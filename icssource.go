@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+// icsFileSource reads the events of a local iCalendar (.ics) file, such as
+// an export from Fastmail or Nextcloud.
+type icsFileSource struct {
+	path string
+}
+
+func (s *icsFileSource) name() string {
+	return s.path
+}
+
+func (s *icsFileSource) fetchEvents(
+	_ context.Context,
+	startDate, endDate time.Time,
+	loc *time.Location,
+) ([]*calendar.Event, error) {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	timeMin, timeMax := queryRange(startDate, endDate, loc)
+	return readICSEvents(f, timeMin, timeMax, loc)
+}
+
+// readICSEvents converts the VEVENTs of an iCalendar stream into calendar
+// events, so that they go through the same eventsToIntervals pipeline as the
+// events of Google Calendar. Recurring events are expanded into the instances
+// overlapping [timeMin, timeMax). Floating times and dates are read in loc.
+func readICSEvents(r io.Reader, timeMin, timeMax time.Time, loc *time.Location) ([]*calendar.Event, error) {
+	roots, err := parseICal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*calendar.Event
+	for _, root := range roots {
+		if root.name != "VCALENDAR" {
+			continue
+		}
+		zones := icsZones(root)
+
+		var vevents []*icalComponent
+		overridden := map[string]bool{}
+		for _, c := range root.components {
+			if c.name != "VEVENT" {
+				continue
+			}
+			vevents = append(vevents, c)
+			if p, ok := c.prop("RECURRENCE-ID"); ok {
+				if t, _, err := parseICSTime(p, zones, loc); err == nil {
+					overridden[icsInstanceKey(c.value("UID"), t)] = true
+				}
+			}
+		}
+
+		for _, c := range vevents {
+			evs, err := expandICSEvent(c, zones, loc, timeMin, timeMax, overridden)
+			if err != nil {
+				// one event we cannot read should not hide the rest of
+				// the calendar
+				log.Printf("warning: skipping event %q: %v", c.value("UID"), err)
+				continue
+			}
+			events = append(events, evs...)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return eventSortKey(events[i]) < eventSortKey(events[j])
+	})
+	return events, nil
+}
+
+func eventSortKey(e *calendar.Event) string {
+	if e.Start.DateTime != "" {
+		t, _ := time.Parse(time.RFC3339, e.Start.DateTime)
+		return t.UTC().Format(time.RFC3339)
+	}
+	return e.Start.Date
+}
+
+func icsInstanceKey(uid string, t time.Time) string {
+	return uid + "|" + strconv.FormatInt(t.Unix(), 10)
+}
+
+func expandICSEvent(
+	c *icalComponent,
+	zones map[string]*time.Location,
+	loc *time.Location,
+	timeMin, timeMax time.Time,
+	overridden map[string]bool,
+) ([]*calendar.Event, error) {
+	dtstart, ok := c.prop("DTSTART")
+	if !ok {
+		return nil, fmt.Errorf("missing DTSTART")
+	}
+	start, allDay, err := parseICSTime(dtstart, zones, loc)
+	if err != nil {
+		return nil, err
+	}
+	end, err := icsEventEnd(c, start, allDay, zones, loc)
+	if err != nil {
+		return nil, err
+	}
+	uid := c.value("UID")
+
+	var rule *rrule
+	if v := c.value("RRULE"); v != "" && c.value("RECURRENCE-ID") == "" {
+		if rule, err = parseRRule(v, start.Location()); err != nil {
+			// the first instance is still known to be busy
+			log.Printf("warning: event %q: %v; using only its first instance", uid, err)
+		}
+	}
+	var rdates []icsPeriod
+	if c.value("RECURRENCE-ID") == "" {
+		if rdates, err = icsRdates(c, zones, loc, start, end, allDay); err != nil {
+			return nil, err
+		}
+	}
+	if rule == nil && len(rdates) == 0 {
+		if !end.After(timeMin) || !start.Before(timeMax) {
+			return nil, nil
+		}
+		return []*calendar.Event{newICSEvent(c, uid, start, end, allDay)}, nil
+	}
+
+	exdates, err := icsExdates(c, zones, loc)
+	if err != nil {
+		return nil, err
+	}
+
+	// DTSTART is always the first instance, even when the rule could not
+	// be read
+	instances := []icsPeriod{{start: start, end: end}}
+	if rule != nil {
+		instances = nil
+		for _, s := range rule.occurrences(start, timeMax) {
+			instances = append(instances, icsPeriod{start: s, end: shiftEnd(start, end, s, allDay)})
+		}
+	}
+	for _, p := range rdates {
+		if !slices.ContainsFunc(instances, func(i icsPeriod) bool { return i.start.Equal(p.start) }) {
+			instances = append(instances, p)
+		}
+	}
+
+	var events []*calendar.Event
+	for _, p := range instances {
+		s, e := p.start, p.end
+		if exdates.contains(s, allDay) || overridden[icsInstanceKey(uid, s)] {
+			continue
+		}
+		if !e.After(timeMin) || !s.Before(timeMax) {
+			continue
+		}
+		ev := newICSEvent(c, uid, s, e, allDay)
+		ev.Id = uid + "_" + s.UTC().Format(icsUTCDateTimeFormat)
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// shiftEnd returns the end of the instance starting at s, keeping the
+// length of the original event in calendar days for all-day events and in
+// elapsed time otherwise.
+func shiftEnd(start, end, s time.Time, allDay bool) time.Time {
+	if allDay {
+		days := int(end.Sub(start).Hours()+12) / 24
+		return s.AddDate(0, 0, days)
+	}
+	return s.Add(end.Sub(start))
+}
+
+func newICSEvent(c *icalComponent, uid string, start, end time.Time, allDay bool) *calendar.Event {
+	ev := &calendar.Event{
+		Id:           uid,
+		ICalUID:      uid,
+		Summary:      icalUnescapeText(c.value("SUMMARY")),
+		Description:  icalUnescapeText(c.value("DESCRIPTION")),
+		Location:     icalUnescapeText(c.value("LOCATION")),
+		Status:       strings.ToLower(c.value("STATUS")),
+		Transparency: strings.ToLower(c.value("TRANSP")),
+		Visibility:   strings.ToLower(c.value("CLASS")),
+	}
+	if allDay {
+		ev.Start = &calendar.EventDateTime{Date: start.Format("2006-01-02")}
+		ev.End = &calendar.EventDateTime{Date: end.Format("2006-01-02")}
+	} else {
+		ev.Start = &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)}
+		ev.End = &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)}
+	}
+	if p, ok := c.prop("ORGANIZER"); ok {
+		ev.Organizer = &calendar.EventOrganizer{Email: icsMailto(p.value), DisplayName: p.params["CN"]}
+	}
+	for _, p := range c.all("ATTENDEE") {
+		ev.Attendees = append(ev.Attendees, &calendar.EventAttendee{
+			Email:          icsMailto(p.value),
+			DisplayName:    p.params["CN"],
+			ResponseStatus: icsResponseStatus(p.params["PARTSTAT"]),
+		})
+	}
+	return ev
+}
+
+func icsMailto(v string) string {
+	if len(v) >= 7 && strings.EqualFold(v[:7], "mailto:") {
+		return v[7:]
+	}
+	return v
+}
+
+// icsResponseStatus maps PARTSTAT to the responseStatus values of the
+// Google Calendar API.
+func icsResponseStatus(partstat string) string {
+	switch strings.ToUpper(partstat) {
+	case "ACCEPTED":
+		return "accepted"
+	case "DECLINED":
+		return "declined"
+	case "TENTATIVE":
+		return "tentative"
+	default:
+		return "needsAction"
+	}
+}
+
+func icsEventEnd(
+	c *icalComponent,
+	start time.Time,
+	allDay bool,
+	zones map[string]*time.Location,
+	loc *time.Location,
+) (time.Time, error) {
+	if p, ok := c.prop("DTEND"); ok {
+		end, _, err := parseICSTime(p, zones, loc)
+		return end, err
+	}
+	if v := c.value("DURATION"); v != "" {
+		return addICSDuration(start, v)
+	}
+	if allDay {
+		return start.AddDate(0, 0, 1), nil
+	}
+	return start, nil
+}
+
+// parseICSTime parses a DATE or DATE-TIME property value. UTC times keep
+// their "Z", times with a TZID are read in that zone, and floating times and
+// dates are read in loc.
+func parseICSTime(p icalProp, zones map[string]*time.Location, loc *time.Location) (t time.Time, allDay bool, err error) {
+	v := p.value
+	if strings.EqualFold(p.params["VALUE"], "DATE") || len(v) == len("20060102") {
+		t, err = time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err = time.Parse(icsUTCDateTimeFormat, v)
+		return t, false, err
+	}
+	zone := loc
+	if tzid := p.params["TZID"]; tzid != "" {
+		zone = resolveTZID(tzid, zones, loc)
+	}
+	t, err = time.ParseInLocation(icsDateTimeFormat, v, zone)
+	return t, false, err
+}
+
+func resolveTZID(tzid string, zones map[string]*time.Location, loc *time.Location) *time.Location {
+	if z, ok := zones[tzid]; ok {
+		return z
+	}
+	if z := loadTZID(tzid); z != nil {
+		return z
+	}
+	return loc
+}
+
+// loadTZID loads an IANA zone from a TZID, also accepting prefixed IDs such
+// as "/mozilla.org/20050126_1/Europe/Berlin".
+func loadTZID(tzid string) *time.Location {
+	if z, err := time.LoadLocation(tzid); err == nil {
+		return z
+	}
+	parts := strings.Split(strings.Trim(tzid, "/"), "/")
+	for i := 1; i < len(parts); i++ {
+		if z, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+			return z
+		}
+	}
+	return nil
+}
+
+// icsZones maps the TZIDs of the VTIMEZONEs in cal to locations. Unknown
+// zones (e.g., Windows names such as "Tokyo Standard Time") fall back to the
+// fixed standard offset given in the VTIMEZONE.
+func icsZones(cal *icalComponent) map[string]*time.Location {
+	zones := map[string]*time.Location{}
+	for _, c := range cal.components {
+		if c.name != "VTIMEZONE" {
+			continue
+		}
+		tzid := c.value("TZID")
+		if z := loadTZID(tzid); z != nil {
+			zones[tzid] = z
+			continue
+		}
+		for _, sub := range c.components {
+			if sub.name != "STANDARD" {
+				continue
+			}
+			if offset, err := parseICSOffset(sub.value("TZOFFSETTO")); err == nil {
+				zones[tzid] = time.FixedZone(tzid, offset)
+				break
+			}
+		}
+	}
+	return zones
+}
+
+func parseICSOffset(v string) (int, error) {
+	if len(v) != 5 && len(v) != 7 {
+		return 0, fmt.Errorf("invalid UTC offset %q", v)
+	}
+	n, err := strconv.Atoi(v[1:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid UTC offset %q", v)
+	}
+	if len(v) == 5 {
+		n *= 100
+	}
+	offset := n/10000*3600 + n/100%100*60 + n%100
+	switch v[0] {
+	case '+':
+		return offset, nil
+	case '-':
+		return -offset, nil
+	default:
+		return 0, fmt.Errorf("invalid UTC offset %q", v)
+	}
+}
+
+var icsDurationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func addICSDuration(t time.Time, v string) (time.Time, error) {
+	m := icsDurationPattern.FindStringSubmatch(v)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid duration %q", v)
+	}
+	n := func(s string) int {
+		i, _ := strconv.Atoi(s)
+		return i
+	}
+	sign := 1
+	if m[1] == "-" {
+		sign = -1
+	}
+	days := sign * (n(m[2])*7 + n(m[3]))
+	d := time.Duration(sign) * (time.Duration(n(m[4]))*time.Hour +
+		time.Duration(n(m[5]))*time.Minute + time.Duration(n(m[6]))*time.Second)
+	return t.AddDate(0, 0, days).Add(d), nil
+}
+
+// icsPeriod is an instance of a recurring event.
+type icsPeriod struct {
+	start, end time.Time
+}
+
+// icsRdates returns the extra instances of RDATE properties. Dates and
+// date-times last as long as the event from start to end; periods have
+// their own end.
+func icsRdates(
+	c *icalComponent,
+	zones map[string]*time.Location,
+	loc *time.Location,
+	start, end time.Time,
+	allDay bool,
+) ([]icsPeriod, error) {
+	var out []icsPeriod
+	for _, p := range c.all("RDATE") {
+		params := map[string]string{}
+		for k, v := range p.params {
+			if k != "VALUE" || !strings.EqualFold(v, "PERIOD") {
+				params[k] = v
+			}
+		}
+		for _, v := range strings.Split(p.value, ",") {
+			startValue, endValue, isPeriod := strings.Cut(v, "/")
+			s, _, err := parseICSTime(icalProp{name: p.name, params: params, value: startValue}, zones, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RDATE: %w", err)
+			}
+			var e time.Time
+			switch {
+			case !isPeriod:
+				e = shiftEnd(start, end, s, allDay)
+			case strings.HasPrefix(strings.TrimLeft(endValue, "+-"), "P"):
+				e, err = addICSDuration(s, endValue)
+			default:
+				e, _, err = parseICSTime(icalProp{name: p.name, params: params, value: endValue}, zones, loc)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid RDATE: %w", err)
+			}
+			out = append(out, icsPeriod{start: s, end: e})
+		}
+	}
+	return out, nil
+}
+
+type icsExdateSet []time.Time
+
+func icsExdates(c *icalComponent, zones map[string]*time.Location, loc *time.Location) (icsExdateSet, error) {
+	var out icsExdateSet
+	for _, p := range c.all("EXDATE") {
+		for _, v := range strings.Split(p.value, ",") {
+			t, _, err := parseICSTime(icalProp{name: p.name, params: p.params, value: v}, zones, loc)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+func (s icsExdateSet) contains(t time.Time, allDay bool) bool {
+	for _, x := range s {
+		if allDay {
+			if x.Format("20060102") == t.Format("20060102") {
+				return true
+			}
+		} else if x.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestICSFileSource(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	startDate := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+	endDate := time.Date(2025, 1, 17, 0, 0, 0, 0, loc)
+
+	src := &icsFileSource{path: "testdata/calendar.ics"}
+	events, err := src.fetchEvents(context.Background(), startDate, endDate, loc)
+	if err != nil {
+		t.Fatalf("fetchEvents() error = %v", err)
+	}
+
+	type row struct{ summary, start, end string }
+	want := []row{
+		{"Daily standup (moved)", "2025-01-13T11:00:00+09:00", "2025-01-13T11:30:00+09:00"},
+		{"Design review, round 2", "2025-01-14T14:00:00+09:00", "2025-01-14T15:30:00+09:00"},
+		{"Lunch", "2025-01-16T03:00:00Z", "2025-01-16T04:00:00Z"},
+		{"Offsite", "2025-01-17", "2025-01-18"},
+		{"Daily standup", "2025-01-17T09:30:00+09:00", "2025-01-17T10:00:00+09:00"},
+	}
+	if len(events) != len(want) {
+		for _, e := range events {
+			t.Logf("%s %+v %+v", e.Summary, e.Start, e.End)
+		}
+		t.Fatalf("fetchEvents() returned %d events, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		got := row{e.Summary, e.Start.DateTime + e.Start.Date, e.End.DateTime + e.End.Date}
+		if got != want[i] {
+			t.Errorf("event %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if events[1].Location != "Room A" {
+		t.Errorf("Location = %q, want %q", events[1].Location, "Room A")
+	}
+	if events[2].Transparency != "transparent" {
+		t.Errorf("Transparency = %q, want %q", events[2].Transparency, "transparent")
+	}
+}
+
+func TestICSFileSourceFreeSlots(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	day := time.Date(2025, 1, 14, 0, 0, 0, 0, loc)
+
+	src := &icsFileSource{path: "testdata/calendar.ics"}
+	events, err := src.fetchEvents(context.Background(), day, day, loc)
+	if err != nil {
+		t.Fatalf("fetchEvents() error = %v", err)
+	}
+	busy := eventsToIntervals(events, loc, eventOptions{})
+	got := findFreeSlots(
+		time.Date(2025, 1, 14, 9, 0, 0, 0, loc),
+		time.Date(2025, 1, 14, 17, 0, 0, 0, loc),
+		busy, slotOptions{minDur: time.Hour})
+
+	want := []string{"09:00~14:00", "15:30~17:00"}
+	if len(got) != len(want) {
+		t.Fatalf("findFreeSlots() = %v, want %v", got, want)
+	}
+	for i, s := range got {
+		if formatSlot(s) != want[i] {
+			t.Errorf("slot %d = %s, want %s", i, formatSlot(s), want[i])
+		}
+	}
+}
+
+func TestAddICSDuration(t *testing.T) {
+	base := time.Date(2025, 1, 13, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"PT1H30M", base.Add(90 * time.Minute)},
+		{"P1D", base.AddDate(0, 0, 1)},
+		{"P1W", base.AddDate(0, 0, 7)},
+		{"P1DT2H", base.AddDate(0, 0, 1).Add(2 * time.Hour)},
+		{"-PT15M", base.Add(-15 * time.Minute)},
+	}
+	for _, tt := range tests {
+		got, err := addICSDuration(base, tt.in)
+		if err != nil {
+			t.Errorf("addICSDuration(%q) error = %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("addICSDuration(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := addICSDuration(base, "1H"); err == nil {
+		t.Error(`addICSDuration("1H") error = nil, want error`)
+	}
+}
+
+// Events with rules that cannot be expanded keep only their first instance,
+// and do not hide the other events of the calendar.
+func TestReadICSEventsUnsupportedRules(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	const ics = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:last-weekday\r\nSUMMARY:Month-end close\r\n" +
+		"DTSTART:20250131T150000\r\nDTEND:20250131T160000\r\n" +
+		"RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:hourly\r\nSUMMARY:Check mail\r\n" +
+		"DTSTART:20250113T090000\r\nDTEND:20250113T091000\r\n" +
+		"RRULE:FREQ=HOURLY\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly\r\nSUMMARY:1on1\r\n" +
+		"DTSTART:20250113T100000\r\nDTEND:20250113T103000\r\n" +
+		"RRULE:FREQ=WEEKLY;WKST=MO\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := readICSEvents(strings.NewReader(ics),
+		time.Date(2025, 1, 13, 0, 0, 0, 0, loc), time.Date(2025, 1, 25, 0, 0, 0, 0, loc), loc)
+	if err != nil {
+		t.Fatalf("readICSEvents() error = %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Summary+" "+e.Start.DateTime)
+	}
+	want := []string{
+		"Check mail 2025-01-13T09:00:00+09:00",
+		"1on1 2025-01-13T10:00:00+09:00",
+		"1on1 2025-01-20T10:00:00+09:00",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readICSEvents() = %v, want %v", got, want)
+	}
+}
+
+func TestReadICSEventsRdate(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	const ics = "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:review\r\nSUMMARY:Review\r\n" +
+		"DTSTART:20250113T100000\r\nDTEND:20250113T110000\r\n" +
+		"RDATE:20250115T140000,20250113T100000\r\n" +
+		"RDATE;VALUE=PERIOD:20250116T080000Z/PT30M\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:standup\r\nSUMMARY:Standup\r\n" +
+		"DTSTART:20250113T093000\r\nDTEND:20250113T094500\r\n" +
+		"RRULE:FREQ=WEEKLY;COUNT=1\r\nRDATE:20250117T093000\r\nEXDATE:20250113T093000\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	events, err := readICSEvents(strings.NewReader(ics),
+		time.Date(2025, 1, 13, 0, 0, 0, 0, loc), time.Date(2025, 1, 20, 0, 0, 0, 0, loc), loc)
+	if err != nil {
+		t.Fatalf("readICSEvents() error = %v", err)
+	}
+	var got []string
+	for _, e := range events {
+		got = append(got, e.Summary+" "+e.Start.DateTime+" "+e.End.DateTime)
+	}
+	want := []string{
+		"Review 2025-01-13T10:00:00+09:00 2025-01-13T11:00:00+09:00",
+		"Review 2025-01-15T14:00:00+09:00 2025-01-15T15:00:00+09:00",
+		"Review 2025-01-16T08:00:00Z 2025-01-16T08:30:00Z",
+		"Standup 2025-01-17T09:30:00+09:00 2025-01-17T09:45:00+09:00",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("readICSEvents() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// locale holds the words and formats of the human-readable output.
+type locale struct {
+	// weekdays are the short weekday names, indexed by time.Weekday.
+	weekdays [7]string
+	// dateLayout is the time layout of the date in a day heading. Month
+	// and weekday names in it are always English, so only use them in
+	// English locales.
+	dateLayout string
+	// dayFormat formats a day heading from the date (%[1]s) and the
+	// weekday name (%[2]s).
+	dayFormat string
+	// headingSep separates a day heading from its slots.
+	headingSep string
+	// rangeSep separates the start and end of a slot.
+	rangeSep string
+	// listSep separates the slots of a day.
+	listSep string
+	// softFormat formats the soft busy times of a day (%s).
+	softFormat string
+	// noSlots is printed when no free slot was found at all.
+	noSlots string
+	// dayShiftFormat marks a slot shown in another timezone whose date
+	// differs by %+d days (see -display-tz).
+	dayShiftFormat string
+}
+
+var localeJa = locale{
+	weekdays:       [7]string{"日", "月", "火", "水", "木", "金", "土"},
+	dateLayout:     "2006-01-02",
+	dayFormat:      "%[1]s（%[2]s）",
+	headingSep:     " ",
+	rangeSep:       "~",
+	listSep:        ", ",
+	softFormat:     "（仮予定: %s）",
+	noSlots:        "空き時間はありません。",
+	dayShiftFormat: "（%+d日）",
+}
+
+var localeEn = locale{
+	weekdays:       [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	dateLayout:     "Jan 2",
+	dayFormat:      "%[2]s, %[1]s",
+	headingSep:     ": ",
+	rangeSep:       "–",
+	listSep:        ", ",
+	softFormat:     " (tentative: %s)",
+	noSlots:        "No free slots.",
+	dayShiftFormat: " (%+dd)",
+}
+
+var locales = map[string]*locale{
+	"ja": &localeJa,
+	"en": &localeEn,
+}
+
+func localeNames() string {
+	names := make([]string, 0, len(locales))
+	for n := range locales {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+func lookupLocale(name string) (*locale, error) {
+	l, ok := locales[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown language %q (want %s)", name, localeNames())
+	}
+	return l, nil
+}
+
+func (l *locale) weekday(t time.Time) string {
+	return l.weekdays[t.Weekday()]
+}
+
+// day formats the heading of a day, e.g. "2025-01-13（月）" or "Mon, Jan 13".
+func (l *locale) day(t time.Time) string {
+	return fmt.Sprintf(l.dayFormat, t.Format(l.dateLayout), l.weekday(t))
+}
+
+// slot formats a slot as "HH:MM~HH:MM" with the locale's range separator.
+func (l *locale) slot(s interval) string {
+	return fmt.Sprintf("%02d:%02d%s%02d:%02d", s.start.Hour(), s.start.Minute(), l.rangeSep, s.end.Hour(), s.end.Minute())
+}
+
+// slots formats a list of slots.
+func (l *locale) slots(ss []interval) string {
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		out = append(out, l.slot(s))
+	}
+	return strings.Join(out, l.listSep)
+}
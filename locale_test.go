@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLookupLocale(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    *locale
+		wantErr bool
+	}{
+		{name: "ja", want: &localeJa},
+		{name: "en", want: &localeEn},
+		{name: "EN", want: &localeEn},
+		{name: "fr", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lookupLocale(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupLocale(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("lookupLocale(%q) = %p, want %p", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMarkdownLocales(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	days := testDays(loc)
+	days[1].soft = []interval{{
+		start: time.Date(2025, 1, 14, 13, 0, 0, 0, loc),
+		end:   time.Date(2025, 1, 14, 14, 0, 0, 0, loc),
+	}}
+
+	tests := []struct {
+		lang *locale
+		days []daySlots
+		want string
+	}{
+		{
+			lang: &localeJa,
+			days: days,
+			want: "- 2025-01-13（月） 09:00~10:00, 14:00~15:30\n" +
+				"- 2025-01-14（火） 10:30~12:00（仮予定: 13:00~14:00）\n",
+		},
+		{
+			lang: &localeEn,
+			days: days,
+			want: "- Mon, Jan 13: 09:00–10:00, 14:00–15:30\n" +
+				"- Tue, Jan 14: 10:30–12:00 (tentative: 13:00–14:00)\n",
+		},
+		{lang: &localeJa, want: "空き時間はありません。\n"},
+		{lang: &localeEn, want: "No free slots.\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := writeOutput(&buf, formatMarkdown, tt.days, outputOptions{loc: loc, lang: tt.lang}); err != nil {
+			t.Fatalf("writeOutput() error = %v", err)
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("writeOutput() =\n%s\nwant\n%s", got, tt.want)
+		}
+	}
+}
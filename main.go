@@ -1,6 +1,8 @@
-// Google Calendar API（OAuth2）でイベントを取得し、
-// 平日 9:00–17:00 の「連続 min 分以上の空き」を Markdown で出力します。
-// 同日の複数スロットはカンマ区切り、日本語曜日を付与します。
+// Google Calendar API（OAuth2）、Microsoft Graph、CalDAV またはローカルの .ics ファイルからイベントを取得し、
+// 勤務日（既定は月〜金、祝日を除く）9:00–17:00 の「連続 min 分以上の空き」を Markdown で出力します。
+// 同日の複数スロットはカンマ区切り、曜日を付与します（-lang en で英語表記）。
+// -calendar を複数指定した場合は、すべてのカレンダーが空いている時間のみを出力します。
+// -start / -end には YYYY-MM-DD のほか today, +3d, next-week, 2025-W03 などの相対指定も使えます（-start の既定は today）。
 // 例:
 //
 //	go mod init example.com/freecalapi
@@ -28,9 +30,11 @@ import (
 	"net/http"
 	"os"
 	"os/exec"
+	"regexp"
 	"runtime"
 	"sort"
 	"strings"
+	"text/template"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -42,6 +46,9 @@ import (
 type interval struct {
 	start time.Time
 	end   time.Time
+	// soft marks busy time that does not block a slot but is reported
+	// alongside it, such as tentatively accepted invitations.
+	soft bool
 }
 
 func mustParseClock(s string) (h, m int) {
@@ -52,27 +59,9 @@ func mustParseClock(s string) (h, m int) {
 	return t.Hour(), t.Minute()
 }
 
-const sundayJP = "日"
-
+// formatJpWeekday returns the Japanese short weekday name of t, e.g. "月".
 func formatJpWeekday(t time.Time) string {
-	switch t.Weekday() {
-	case time.Monday:
-		return "月"
-	case time.Tuesday:
-		return "火"
-	case time.Wednesday:
-		return "水"
-	case time.Thursday:
-		return "木"
-	case time.Friday:
-		return "金"
-	case time.Saturday:
-		return "土"
-	case time.Sunday:
-		return sundayJP
-	default:
-		return sundayJP
-	}
+	return localeJa.weekday(t)
 }
 
 func overlaps(a, b interval) (interval, bool) {
@@ -118,7 +107,13 @@ func getClient(ctx context.Context, credentialsPath, tokenPath string, scopes ..
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse credentials: %w", err)
 	}
+	return getTokenSource(ctx, config, tokenPath)
+}
 
+// getTokenSource loads the token saved at tokenPath, refreshing it when it
+// has expired, or runs the browser flow when there is no usable one. New
+// and refreshed tokens are saved.
+func getTokenSource(ctx context.Context, config *oauth2.Config, tokenPath string) (oauth2.TokenSource, error) {
 	// Try load saved token
 	var tok *oauth2.Token
 	if f, err := os.Open(tokenPath); err == nil {
@@ -129,6 +124,22 @@ func getClient(ctx context.Context, credentialsPath, tokenPath string, scopes ..
 		}
 	}
 
+	// Access tokens are short-lived (about an hour for Microsoft Graph), so
+	// an expired one is renewed with its refresh token instead of asking to
+	// sign in again.
+	if tok != nil && !tok.Valid() && tok.RefreshToken != "" {
+		refreshed, err := config.TokenSource(ctx, tok).Token()
+		if err != nil {
+			log.Printf("warning: failed to refresh token: %v", err)
+			tok = nil
+		} else {
+			tok = refreshed
+			if err := saveToken(tokenPath, tok); err != nil {
+				log.Printf("warning: failed to save token: %v", err)
+			}
+		}
+	}
+
 	if tok == nil || !tok.Valid() {
 		// Use local redirect server for OAuth flow
 		tok = getTokenFromWeb(ctx, config)
@@ -160,14 +171,17 @@ func getTokenFromWeb(ctx context.Context, config *oauth2.Config) *oauth2.Token {
 	codeCh := make(chan string, 1)
 	errorCh := make(chan error, 1)
 
-	// Use localhost with a random available port
+	// Use localhost with a random available port. Each flow gets its own mux,
+	// so that more than one provider can authenticate in a single run.
+	mux := http.NewServeMux()
 	server := &http.Server{
 		Addr:              "localhost:0",
+		Handler:           mux,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
 
 	// Setup handler for OAuth callback
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
 		code := r.URL.Query().Get("code")
 		if code == "" {
 			errorCh <- fmt.Errorf("no code in callback")
@@ -221,8 +235,8 @@ func getTokenFromWeb(ctx context.Context, config *oauth2.Config) *oauth2.Token {
 	// Generate auth URL
 	authURL := config.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
 
-	fmt.Printf("Opening browser for authentication...\n")
-	fmt.Printf("If browser doesn't open automatically, please visit:\n%s\n\n", authURL)
+	fmt.Fprintf(os.Stderr, "Opening browser for authentication...\n")
+	fmt.Fprintf(os.Stderr, "If browser doesn't open automatically, please visit:\n%s\n\n", authURL)
 
 	// Try to open browser automatically
 	openBrowser(authURL)
@@ -231,7 +245,7 @@ func getTokenFromWeb(ctx context.Context, config *oauth2.Config) *oauth2.Token {
 	var code string
 	select {
 	case code = <-codeCh:
-		fmt.Println("Authorization code received!")
+		fmt.Fprintln(os.Stderr, "Authorization code received!")
 	case serverErr := <-errorCh:
 		log.Fatalf("server error: %v", serverErr)
 	case <-time.After(5 * time.Minute):
@@ -275,34 +289,228 @@ func openBrowser(url string) {
 
 // -----------------------------------------------------------
 
+// stringList is a flag.Value that accumulates values from repeated flags.
+// Each occurrence may also hold several comma-separated values.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
+	}
+	return nil
+}
+
+// rawList is a repeatable flag whose values are kept as given, for values
+// that may contain commas such as regular expressions or weekday lists.
+type rawList []string
+
+func (l *rawList) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *rawList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
 type config struct {
 	credentialsPath string
 	tokenPath       string
-	calendarID      string
+	calendarIDs     stringList
+	freeBusyIDs     stringList
+	icsPaths        stringList
+	caldavURLs      stringList
+	caldavUser      string
+	caldavPassword  string
+	caldavToken     string
+	outlookIDs      stringList
+	msClientID      string
+	msClientSecret  string
+	msTenant        string
+	msTokenPath     string
 	startStr        string
 	endStr          string
+	days            int
 	workStart       string
 	workEnd         string
+	workdays        string
+	hours           string
+	excludes        rawList
+	participants    rawList
+	ruleSpecs       []ruleSpec
+	dryRun          bool
+	eventOptions    eventOptions
 	minMinutes      int
+	granularity     time.Duration
+	durationMinutes int
+	stepMinutes     int
+	maxPerDay       int
 	tzName          string
+	displayTZs      stringList
+	format          string
+	holidays        string
+	lang            string
+	templateName    string
+}
+
+// hasCalendar reports whether id is one of the calendars, files or URLs to
+// read busy times from.
+func (c *config) hasCalendar(id string) bool {
+	for _, ids := range []stringList{c.calendarIDs, c.freeBusyIDs, c.icsPaths, c.caldavURLs, c.outlookIDs} {
+		for _, v := range ids {
+			if v == id {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func parseFlags() *config {
 	c := &config{}
 	flag.StringVar(&c.credentialsPath, "credentials", "",
-		"Path to OAuth client credentials (credentials.json)")
+		"Path to OAuth client credentials (credentials.json); required for Google calendars")
 	flag.StringVar(&c.tokenPath, "token", "token.json", "Path to save/load OAuth token")
-	flag.StringVar(&c.calendarID, "calendar", "primary",
-		"Calendar ID (e.g., primary or somebody@example.com)")
-	flag.StringVar(&c.startStr, "start", "", "Start date (YYYY-MM-DD)")
-	flag.StringVar(&c.endStr, "end", "", "End date (YYYY-MM-DD)")
+	flag.Var(&c.calendarIDs, "calendar",
+		"Calendar ID (e.g., primary or somebody@example.com); repeat or comma-separate for multiple (default primary)")
+	flag.Var(&c.freeBusyIDs, "freebusy",
+		"Calendar ID to query with the FreeBusy API (free/busy access only); repeat or comma-separate for multiple")
+	flag.Var(&c.icsPaths, "ics", "Path to a local iCalendar (.ics) file to read events from; repeat or comma-separate for multiple")
+	flag.Var(&c.caldavURLs, "caldav", "CalDAV calendar collection URL; repeat or comma-separate for multiple")
+	flag.StringVar(&c.caldavUser, "caldav-user", "", "CalDAV username for Basic auth")
+	flag.StringVar(&c.caldavPassword, "caldav-password", "",
+		"CalDAV password for Basic auth (default $FREECAL_CALDAV_PASSWORD)")
+	flag.StringVar(&c.caldavToken, "caldav-token", "",
+		"CalDAV token for Bearer auth (default $FREECAL_CALDAV_TOKEN)")
+	flag.Var(&c.outlookIDs, "outlook",
+		"Microsoft 365 / Outlook mailbox to query with Microsoft Graph (e.g., me@example.com); repeat or comma-separate for multiple")
+	flag.StringVar(&c.msClientID, "ms-client-id", "", "Application (client) ID registered in Microsoft Entra ID")
+	flag.StringVar(&c.msClientSecret, "ms-client-secret", "",
+		"Client secret, if the application is not a public client (default $FREECAL_MS_CLIENT_SECRET)")
+	flag.StringVar(&c.msTenant, "ms-tenant", "common", "Microsoft Entra tenant (common, organizations or a tenant ID)")
+	flag.StringVar(&c.msTokenPath, "ms-token", "ms_token.json", "Path to save/load the Microsoft OAuth token")
+	flag.StringVar(&c.startStr, "start", "today",
+		"Start date: YYYY-MM-DD, today, tomorrow, +3d, +2w, this-week, next-week, this-month, next-month or 2025-W03")
+	flag.StringVar(&c.endStr, "end", "",
+		"End date, in the same forms as -start (default the end of -start, e.g. the Sunday of next-week)")
+	flag.IntVar(&c.days, "days", 0, "Number of days to search from -start, instead of -end")
 	flag.StringVar(&c.workStart, "workstart", "09:00", "Workday start (HH:MM)")
 	flag.StringVar(&c.workEnd, "workend", "17:00", "Workday end (HH:MM)")
-	flag.IntVar(&c.minMinutes, "min", 60, "Minimum free slot length in minutes")
+	flag.StringVar(&c.workdays, "workdays", "mon-fri", "Working days (e.g., mon,tue,wed,thu,fri or sun-thu)")
+	flag.Var(&c.excludes, "exclude",
+		"Recurring time to treat as busy, optionally on some weekdays only (e.g., 12:00-13:00 or mon-fri=09:30-09:45); repeatable")
+	flag.StringVar(&c.hours, "hours", "",
+		"Working hours per weekday, overriding -workstart/-workend (e.g., mon=10:00-18:00,fri=09:00-12:00,fri=13:00-15:00)")
+	flag.Var(&c.participants, "participant",
+		"Timezone and working hours of one of the calendars, e.g. calendar=bob@example.com;tz=Europe/London;hours=09:00-17:00; repeatable")
+	flag.IntVar(&c.minMinutes, "min", 60, "Minimum free slot length in minutes (after buffers)")
+	flag.IntVar(&c.durationMinutes, "duration", 0,
+		"Meeting length in minutes; when set, list candidate start times instead of free slots and ignore -min")
+	flag.IntVar(&c.stepMinutes, "step", 30, "Minutes between candidate start times (with -duration)")
+	flag.IntVar(&c.maxPerDay, "max-per-day", 0, "Maximum number of candidates per day (with -duration, default unlimited)")
+	flag.DurationVar(&c.granularity, "granularity", 0, "Align slot boundaries to this grid, e.g. 15m or 30m (default off)")
+	needsAction := flag.String("needs-action", string(treatBusy),
+		"How to treat invitations I have not answered: busy, free or soft (shown separately)")
+	tentative := flag.String("tentative", string(treatBusy),
+		"How to treat invitations I accepted tentatively: busy, free or soft (shown separately)")
+	flag.StringVar(&c.eventOptions.allDay, "allday", allDayBusy,
+		"How to treat all-day events: busy, ignore, or ooo-only (only leave such as out of office or 休暇 blocks the day)")
+	focusTime := flag.String("focus-time", string(treatBusy),
+		"How to treat Google Calendar focus time: busy, free or soft (shown separately)")
+	flag.Var(&ruleFlag{action: treatFree, specs: &c.ruleSpecs}, "ignore",
+		"Rule for events to ignore, as key=value pairs separated by ';' (keys: summary, color, type, organizer, visibility, calendar); repeatable")
+	flag.Var(&ruleFlag{action: treatSoft, specs: &c.ruleSpecs}, "soft", "Rule for events to treat as soft busy (same syntax as -ignore); repeatable")
+	flag.BoolVar(&c.dryRun, "dry-run", false, "List the events matched by each -ignore/-soft rule instead of free slots")
+	flag.DurationVar(&c.eventOptions.bufferBefore, "buffer-before", 0, "Free time to keep before online meetings (e.g., 10m)")
+	flag.DurationVar(&c.eventOptions.bufferAfter, "buffer-after", 0, "Free time to keep after online meetings (e.g., 10m)")
+	flag.DurationVar(&c.eventOptions.onsiteBufferBefore, "buffer-before-onsite", 0,
+		"Free time to keep before meetings with a physical location (e.g., 30m)")
+	flag.DurationVar(&c.eventOptions.onsiteBufferAfter, "buffer-after-onsite", 0,
+		"Free time to keep after meetings with a physical location (e.g., 30m)")
 	flag.StringVar(&c.tzName, "tz", "Asia/Tokyo", "IANA timezone (e.g., Asia/Tokyo)")
-	flag.Parse()
+	flag.Var(&c.displayTZs, "display-tz",
+		"Also show every slot in this IANA timezone (e.g., America/Los_Angeles); repeat or comma-separate for multiple")
+	flag.StringVar(&c.format, "format", formatMarkdown, "Output format ("+formatNames()+")")
+	flag.StringVar(&c.holidays, "holidays", "jp", "Public holidays to skip like weekends ("+holidayCalendarNames()+")")
+	flag.StringVar(&c.lang, "lang", "ja", "Language of weekday names, dates and messages in the output ("+localeNames()+")")
+	flag.StringVar(&c.templateName, "template", "",
+		"Write the slots with a text/template file, or a built-in template ("+builtinTemplateNames()+"), instead of -format")
+	configPath := flag.String("config", "",
+		"Path to the config file (default "+defaultConfigPath()+")")
+	profile := flag.String("profile", os.Getenv("FREECAL_PROFILE"),
+		"Profile of the config file to use (default $FREECAL_PROFILE)")
 
-	if c.credentialsPath == "" || c.startStr == "" || c.endStr == "" {
+	// "freecal config [flags]" prints the effective configuration.
+	args := os.Args[1:]
+	printConfig := len(args) > 0 && args[0] == "config"
+	if printConfig {
+		args = args[1:]
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		os.Exit(2)
+	}
+
+	path, explicit := *configPath, *configPath != ""
+	if !explicit {
+		path = defaultConfigPath()
+	}
+	cf, err := loadConfigFile(path, explicit)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	sources, err := applyConfig(flag.CommandLine, cf, *profile, os.Getenv)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if printConfig {
+		fmt.Printf("# config file: %s\n", path)
+		if *profile != "" {
+			fmt.Printf("# profile: %s\n", *profile)
+		}
+		if err := writeEffectiveConfig(os.Stdout, flag.CommandLine, sources); err != nil {
+			log.Fatalf("failed to write output: %v", err)
+		}
+		os.Exit(0)
+	}
+
+	if c.eventOptions.needsAction, err = parseTreatment(*needsAction); err != nil {
+		log.Fatalf("invalid -needs-action: %v", err)
+	}
+	if c.eventOptions.tentative, err = parseTreatment(*tentative); err != nil {
+		log.Fatalf("invalid -tentative: %v", err)
+	}
+	if c.eventOptions.focusTime, err = parseTreatment(*focusTime); err != nil {
+		log.Fatalf("invalid -focus-time: %v", err)
+	}
+	for _, s := range c.ruleSpecs {
+		r, err := parseEventRule(s.spec, s.action)
+		if err != nil {
+			log.Fatalf("invalid -%s: %v", ruleActionName(s.action), err)
+		}
+		c.eventOptions.rules = append(c.eventOptions.rules, r)
+	}
+	if !isValidAllDayMode(c.eventOptions.allDay) {
+		log.Fatalf("invalid -allday: %q (want busy, ignore or ooo-only)", c.eventOptions.allDay)
+	}
+	if c.granularity < 0 {
+		log.Fatalf("invalid -granularity: %v", c.granularity)
+	}
+	if c.durationMinutes < 0 || c.stepMinutes <= 0 || c.maxPerDay < 0 {
+		log.Fatalf("-duration and -max-per-day must not be negative, and -step must be positive")
+	}
+	if len(c.calendarIDs) == 0 && len(c.freeBusyIDs) == 0 && len(c.icsPaths) == 0 &&
+		len(c.caldavURLs) == 0 && len(c.outlookIDs) == 0 {
+		c.calendarIDs = stringList{"primary"}
+	}
+	usesGoogle := len(c.calendarIDs) > 0 || len(c.freeBusyIDs) > 0
+	if (usesGoogle && c.credentialsPath == "") || (len(c.outlookIDs) > 0 && c.msClientID == "") ||
+		c.days < 0 || !isValidFormat(c.format) {
 		flag.Usage()
 		os.Exit(2)
 	}
@@ -354,7 +562,16 @@ func parseEventTime(e *calendar.Event, loc *time.Location) (start, end time.Time
 		if err1 != nil || err2 != nil {
 			return time.Time{}, time.Time{}, false
 		}
-		return ss.In(loc), ee.In(loc), true
+		start, end = ss.In(loc), ee.In(loc)
+		if e.EventType == eventTypeOutOfOffice {
+			// out of office blocks the whole days it touches, even when it
+			// is only entered for part of a day
+			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+			if !end.Equal(time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc)) {
+				end = time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, loc)
+			}
+		}
+		return start, end, true
 	}
 
 	if e.Start != nil && e.Start.Date != "" && e.End != nil && e.End.Date != "" {
@@ -373,15 +590,187 @@ func parseEventTime(e *calendar.Event, loc *time.Location) (start, end time.Time
 	return time.Time{}, time.Time{}, false
 }
 
-func eventsToIntervals(events []*calendar.Event, loc *time.Location) []interval {
+// Google Calendar event types with a special meaning. Other types, such as
+// "default" and "fromGmail", are ordinary events.
+const (
+	eventTypeOutOfOffice     = "outOfOffice"
+	eventTypeFocusTime       = "focusTime"
+	eventTypeWorkingLocation = "workingLocation"
+)
+
+// How all-day events are treated (-allday).
+const (
+	allDayBusy    = "busy"
+	allDayIgnore  = "ignore"
+	allDayOOOOnly = "ooo-only"
+)
+
+func isValidAllDayMode(mode string) bool {
+	switch mode {
+	case allDayBusy, allDayIgnore, allDayOOOOnly:
+		return true
+	default:
+		return false
+	}
+}
+
+// leaveTitlePattern matches the titles people give to days off.
+var leaveTitlePattern = regexp.MustCompile(`(?i)休み|有給|休暇|\bOOO\b`)
+
+// isLeaveEvent reports whether e looks like a day off rather than a banner
+// such as a birthday or "Sprint 42".
+func isLeaveEvent(e *calendar.Event) bool {
+	return e.EventType == eventTypeOutOfOffice || leaveTitlePattern.MatchString(e.Summary)
+}
+
+// keepAllDayEvent reports whether the all-day event e blocks time in mode.
+func keepAllDayEvent(e *calendar.Event, mode string) bool {
+	switch mode {
+	case allDayIgnore:
+		return false
+	case allDayOOOOnly:
+		return isLeaveEvent(e)
+	default:
+		return true
+	}
+}
+
+// eventOptions controls how events are turned into busy intervals.
+type eventOptions struct {
+	// Buffers widen timed events, e.g. to leave time to prepare or travel.
+	// The onsite buffers apply to events with a physical location, the
+	// others to online-only events and free/busy-only sources.
+	bufferBefore       time.Duration
+	bufferAfter        time.Duration
+	onsiteBufferBefore time.Duration
+	onsiteBufferAfter  time.Duration
+
+	// How to treat invitations I have not answered or accepted tentatively.
+	// Declined invitations are always free.
+	needsAction treatment
+	tentative   treatment
+	// focusTime is how to treat focus time events.
+	focusTime treatment
+	// allDay is how to treat all-day events (allDayBusy, ...).
+	allDay string
+
+	// rules override the treatment of matching events (-ignore, -soft).
+	// source names the calendar the events are read from, for rules that
+	// apply to a single calendar.
+	rules  []eventRule
+	source string
+}
+
+// treatment says whether an event blocks time.
+type treatment string
+
+const (
+	treatBusy treatment = "busy"
+	treatFree treatment = "free"
+	// treatSoft does not block time, but is shown next to the free slots.
+	treatSoft treatment = "soft"
+)
+
+func parseTreatment(s string) (treatment, error) {
+	switch t := treatment(s); t {
+	case treatBusy, treatFree, treatSoft:
+		return t, nil
+	default:
+		return "", fmt.Errorf("invalid value %q (want busy, free or soft)", s)
+	}
+}
+
+// eventTreatment returns how an event is treated according to its type and
+// my response to it. Working location events ("Working from home") only say
+// where I am and never block time.
+func eventTreatment(e *calendar.Event, opts eventOptions) treatment {
+	switch e.EventType {
+	case eventTypeWorkingLocation:
+		return treatFree
+	case eventTypeFocusTime:
+		return opts.focusTime
+	}
+	return rsvpTreatment(e, opts)
+}
+
+// rsvpTreatment returns how an event is treated according to my response
+// to it. Events without my own attendee entry (e.g. ones I organize without
+// guests) are busy.
+func rsvpTreatment(e *calendar.Event, opts eventOptions) treatment {
+	for _, a := range e.Attendees {
+		if !a.Self {
+			continue
+		}
+		switch a.ResponseStatus {
+		case "declined":
+			return treatFree
+		case "needsAction":
+			return opts.needsAction
+		case "tentative":
+			return opts.tentative
+		}
+	}
+	return treatBusy
+}
+
+// isDeclined reports whether I declined the invitation to e.
+func isDeclined(e *calendar.Event) bool {
+	for _, a := range e.Attendees {
+		if a.Self {
+			return a.ResponseStatus == "declined"
+		}
+	}
+	return false
+}
+
+// onlineLocationKeywords mark a location as a video call rather than a place.
+var onlineLocationKeywords = []string{
+	"zoom", "meet.google", "google meet", "teams", "webex", "hangout", "skype", "online", "オンライン",
+}
+
+// isPhysicalLocation reports whether an event location looks like a place
+// people have to go to, as opposed to empty or a video call link.
+func isPhysicalLocation(location string) bool {
+	l := strings.ToLower(strings.TrimSpace(location))
+	if l == "" || strings.Contains(l, "://") {
+		return false
+	}
+	for _, k := range onlineLocationKeywords {
+		if strings.Contains(l, k) {
+			return false
+		}
+	}
+	return true
+}
+
+// widenIntervals returns the intervals extended by before and after.
+func widenIntervals(in []interval, before, after time.Duration) []interval {
+	out := make([]interval, 0, len(in))
+	for _, iv := range in {
+		out = append(out, interval{start: iv.start.Add(-before), end: iv.end.Add(after)})
+	}
+	return out
+}
+
+func eventsToIntervals(events []*calendar.Event, loc *time.Location, opts eventOptions) []interval {
 	busyAll := make([]interval, 0, len(events))
 	for _, e := range events {
-		if strings.EqualFold(e.Status, "canceled") {
+		if strings.EqualFold(e.Status, "cancelled") {
 			continue
 		}
 		if strings.EqualFold(e.Transparency, "transparent") {
 			continue // free events
 		}
+		if e.Start != nil && e.Start.DateTime == "" && !keepAllDayEvent(e, opts.allDay) {
+			continue
+		}
+		t := eventTreatment(e, opts)
+		if i := matchRule(opts.rules, opts.source, e); i >= 0 {
+			t = opts.rules[i].action
+		}
+		if t == treatFree {
+			continue
+		}
 
 		s, en, ok := parseEventTime(e, loc)
 		if !ok {
@@ -390,17 +779,54 @@ func eventsToIntervals(events []*calendar.Event, loc *time.Location) []interval
 		if !en.After(s) {
 			continue
 		}
-		busyAll = append(busyAll, interval{start: s, end: en})
+		if e.Start.DateTime != "" && e.EventType != eventTypeOutOfOffice {
+			if isPhysicalLocation(e.Location) {
+				s, en = s.Add(-opts.onsiteBufferBefore), en.Add(opts.onsiteBufferAfter)
+			} else {
+				s, en = s.Add(-opts.bufferBefore), en.Add(opts.bufferAfter)
+			}
+		}
+		busyAll = append(busyAll, interval{start: s, end: en, soft: t == treatSoft})
 	}
 	return busyAll
 }
 
-func findFreeSlots(dayStart, dayEnd time.Time, busyAll []interval, minDur time.Duration) []string {
+// slotOptions controls which free intervals are reported as slots.
+type slotOptions struct {
+	// minDur is the minimum length of a slot.
+	minDur time.Duration
+	// granularity, when set, aligns slot starts up and ends down to a grid
+	// of this size (e.g. 15m), counted from midnight.
+	granularity time.Duration
+}
+
+// alignToGrid rounds t to a multiple of grid on the wall clock, up or down.
+func alignToGrid(t time.Time, grid time.Duration, up bool) time.Time {
+	if grid <= 0 {
+		return t
+	}
+	sinceMidnight := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+	r := sinceMidnight % grid
+	switch {
+	case r == 0:
+		return t
+	case up:
+		return t.Add(grid - r)
+	default:
+		return t.Add(-r)
+	}
+}
+
+func findFreeSlots(dayStart, dayEnd time.Time, busyAll []interval, opts slotOptions) []interval {
 	dayWin := interval{start: dayStart, end: dayEnd}
 
 	// collect and merge overlaps with day window
 	var busy []interval
 	for _, ev := range busyAll {
+		if ev.soft {
+			continue
+		}
 		if inter, ok := overlaps(ev, dayWin); ok {
 			busy = append(busy, inter)
 		}
@@ -422,17 +848,96 @@ func findFreeSlots(dayStart, dayEnd time.Time, busyAll []interval, minDur time.D
 		free = append(free, interval{start: cursor, end: dayEnd})
 	}
 
-	// filter by min
-	var out []string
+	// align to the grid, then filter by min
+	var out []interval
 	for _, f := range free {
-		if f.end.Sub(f.start) >= minDur {
-			out = append(out, fmt.Sprintf("%02d:%02d~%02d:%02d",
-				f.start.Hour(), f.start.Minute(), f.end.Hour(), f.end.Minute()))
+		f.start = alignToGrid(f.start, opts.granularity, true)
+		f.end = alignToGrid(f.end, opts.granularity, false)
+		if f.end.After(f.start) && f.end.Sub(f.start) >= opts.minDur {
+			out = append(out, f)
 		}
 	}
 	return out
 }
 
+// softOverlaps returns the parts of the soft busy intervals that fall into
+// any of the windows, merged.
+func softOverlaps(busyAll []interval, windows []interval) []interval {
+	var out []interval
+	for _, ev := range busyAll {
+		if !ev.soft {
+			continue
+		}
+		for _, w := range windows {
+			if inter, ok := overlaps(ev, w); ok {
+				out = append(out, inter)
+			}
+		}
+	}
+	return mergeIntervals(out)
+}
+
+// candidateSlots expands free slots into meeting candidates of exactly dur,
+// starting every step within each slot. At most maxCount candidates are
+// returned when maxCount > 0.
+func candidateSlots(free []interval, dur, step time.Duration, maxCount int) []interval {
+	var out []interval
+	for _, f := range free {
+		for s := f.start; !s.Add(dur).After(f.end); s = s.Add(step) {
+			if maxCount > 0 && len(out) >= maxCount {
+				return out
+			}
+			out = append(out, interval{start: s, end: s.Add(dur)})
+		}
+	}
+	return out
+}
+
+// freeDays finds the free slots in the working time and groups them by the
+// day of loc they start on. Slots are found on one timeline rather than day
+// by day, so that a slot crossing midnight in loc, e.g. with participants
+// abroad, is one slot and counts as such for -min and -duration. With
+// meetingDur > 0, each day lists at most maxPerDay candidates.
+func freeDays(
+	working, busyAll []interval,
+	startDate, endDate time.Time,
+	loc *time.Location,
+	opts slotOptions,
+	meetingDur, step time.Duration,
+	maxPerDay int,
+) []daySlots {
+	var free []interval
+	for _, w := range working {
+		// bounds may come from a participant's timezone
+		free = append(free, findFreeSlots(w.start.In(loc), w.end.In(loc), busyAll, opts)...)
+	}
+
+	var days []daySlots
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		dayStart, dayEnd := queryRange(day, day, loc)
+		var out []interval
+		for _, s := range free {
+			if !s.start.Before(dayStart) && s.start.Before(dayEnd) {
+				out = append(out, s)
+			}
+		}
+		if meetingDur > 0 {
+			out = candidateSlots(out, meetingDur, step, maxPerDay)
+		}
+		if len(out) == 0 {
+			continue
+		}
+		var windows []interval
+		for _, w := range working {
+			if inter, ok := overlaps(w, interval{start: dayStart, end: dayEnd}); ok {
+				windows = append(windows, interval{start: inter.start.In(loc), end: inter.end.In(loc)})
+			}
+		}
+		days = append(days, daySlots{date: day, slots: out, soft: softOverlaps(busyAll, windows)})
+	}
+	return days
+}
+
 // -----------------------------------------------------------
 
 func main() {
@@ -443,54 +948,173 @@ func main() {
 		log.Fatalf("failed to load timezone %q: %v", cfg.tzName, err)
 	}
 
-	startDate, err := time.ParseInLocation("2006-01-02", cfg.startStr, loc)
+	startDate, endDate, err := resolveDateRange(cfg.startStr, cfg.endStr, cfg.days, now().In(loc))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	workdays, err := parseWeekdays(cfg.workdays)
 	if err != nil {
-		log.Fatalf("invalid -start: %v", err)
+		log.Fatalf("invalid -workdays: %v", err)
 	}
-	endDate, err := time.ParseInLocation("2006-01-02", cfg.endStr, loc)
+	isHoliday, err := lookupHolidayCalendar(cfg.holidays)
 	if err != nil {
-		log.Fatalf("invalid -end: %v", err)
+		log.Fatalf("invalid -holidays: %v", err)
 	}
-	if endDate.Before(startDate) {
-		log.Fatalf("-end is before -start")
+	lang, err := lookupLocale(cfg.lang)
+	if err != nil {
+		log.Fatalf("invalid -lang: %v", err)
+	}
+	displayZones := make([]*time.Location, 0, len(cfg.displayTZs))
+	for _, name := range cfg.displayTZs {
+		zone, err := time.LoadLocation(name)
+		if err != nil {
+			log.Fatalf("invalid -display-tz %q: %v", name, err)
+		}
+		displayZones = append(displayZones, zone)
+	}
+	var tmpl *template.Template
+	if cfg.templateName != "" {
+		if tmpl, err = loadTemplate(cfg.templateName, lang); err != nil {
+			log.Fatalf("invalid -template: %v", err)
+		}
 	}
 
 	wsH, wsM := mustParseClock(cfg.workStart)
 	weH, weM := mustParseClock(cfg.workEnd)
-
-	ctx := context.Background()
-	ts, err := getClient(ctx, cfg.credentialsPath, cfg.tokenPath, calendar.CalendarReadonlyScope)
+	defWindow := clockWindow{start: wsH*60 + wsM, end: weH*60 + weM}
+	hours, err := parseWeeklyHours(cfg.hours, defWindow)
 	if err != nil {
-		log.Fatalf("unable to get client: %v", err)
+		log.Fatalf("invalid -hours: %v", err)
 	}
-	svc, err := calendar.NewService(ctx, option.WithTokenSource(ts))
-	if err != nil {
-		log.Fatalf("unable to create calendar service: %v", err)
+	own := workSchedule{loc: loc, workdays: workdays, hours: hours, isHoliday: isHoliday}
+	participants := make([]participant, 0, len(cfg.participants))
+	for _, spec := range cfg.participants {
+		p, err := parseParticipant(spec, own, defWindow)
+		if err != nil {
+			log.Fatalf("invalid -participant: %v", err)
+		}
+		if !cfg.hasCalendar(p.calendar) {
+			log.Fatalf("invalid -participant: %s is not one of the calendars to check", p.calendar)
+		}
+		participants = append(participants, p)
+	}
+	exclusions := make([]exclusion, 0, len(cfg.excludes))
+	for _, spec := range cfg.excludes {
+		x, err := parseExclusion(spec)
+		if err != nil {
+			log.Fatalf("invalid -exclude: %v", err)
+		}
+		exclusions = append(exclusions, x)
 	}
 
-	// Fetch events
-	events, err := fetchCalendarEvents(ctx, svc, cfg.calendarID, startDate, endDate, loc)
-	if err != nil {
-		log.Fatalf("events list error: %v", err)
+	ctx := context.Background()
+	sources := make([]eventSource, 0, len(cfg.calendarIDs)+len(cfg.icsPaths)+len(cfg.caldavURLs))
+	var svc *calendar.Service
+	if len(cfg.calendarIDs) > 0 || len(cfg.freeBusyIDs) > 0 {
+		ts, err := getClient(ctx, cfg.credentialsPath, cfg.tokenPath, calendar.CalendarReadonlyScope)
+		if err != nil {
+			log.Fatalf("unable to get client: %v", err)
+		}
+		svc, err = calendar.NewService(ctx, option.WithTokenSource(ts))
+		if err != nil {
+			log.Fatalf("unable to create calendar service: %v", err)
+		}
+		for _, calendarID := range cfg.calendarIDs {
+			sources = append(sources, &googleSource{svc: svc, calendarID: calendarID})
+		}
+	}
+	for _, path := range cfg.icsPaths {
+		sources = append(sources, &icsFileSource{path: path})
+	}
+	for _, u := range cfg.caldavURLs {
+		src, err := newCalDAVSource(u, cfg.caldavUser, cfg.caldavPassword, cfg.caldavToken)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		sources = append(sources, src)
 	}
 
-	// Convert to intervals
-	busyAll := eventsToIntervals(events, loc)
+	// Fetch events of every calendar. A slot is free only when all calendars
+	// are free, so the busy intervals of all of them are simply combined.
+	// Google calendars whose events cannot be read fall back to the FreeBusy API.
+	var busyAll []interval
+	var fetched []sourceEvents
+	freeBusyIDs := append([]string(nil), cfg.freeBusyIDs...)
+	for _, src := range sources {
+		events, err := src.fetchEvents(ctx, startDate, endDate, loc)
+		if err != nil {
+			if g, ok := src.(*googleSource); ok && isNoEventAccess(err) {
+				log.Printf("no access to events of %s, using free/busy instead: %v", g.calendarID, err)
+				freeBusyIDs = append(freeBusyIDs, g.calendarID)
+				continue
+			}
+			log.Fatalf("events list error (%s): %v", src.name(), err)
+		}
+		fetched = append(fetched, sourceEvents{source: src.name(), events: events})
+		opts := cfg.eventOptions
+		opts.source = src.name()
+		busyAll = append(busyAll, eventsToIntervals(events, loc, opts)...)
+	}
+	if cfg.dryRun {
+		if err := writeRuleMatches(os.Stdout, cfg.eventOptions.rules, fetched, loc); err != nil {
+			log.Fatalf("failed to write output: %v", err)
+		}
+		return
+	}
 
-	// Iterate weekdays and print free slots
-	minDur := time.Duration(cfg.minMinutes) * time.Minute
-	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
-		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
-			continue
+	// Sources that only provide free/busy information.
+	var busySources []busySource
+	if len(freeBusyIDs) > 0 {
+		busySources = append(busySources, &freeBusySource{svc: svc, calendarIDs: freeBusyIDs})
+	}
+	if len(cfg.outlookIDs) > 0 {
+		client, err := getMicrosoftClient(ctx, cfg.msClientID, cfg.msClientSecret, cfg.msTenant, cfg.msTokenPath)
+		if err != nil {
+			log.Fatalf("unable to get Microsoft client: %v", err)
+		}
+		busySources = append(busySources, &graphSource{client: client, schedules: cfg.outlookIDs})
+	}
+	for _, src := range busySources {
+		busy, err := src.fetchBusy(ctx, startDate, endDate, loc)
+		if err != nil {
+			log.Fatalf("free/busy query error (%s): %v", src.name(), err)
 		}
+		busyAll = append(busyAll, widenIntervals(busy, cfg.eventOptions.bufferBefore, cfg.eventOptions.bufferAfter)...)
+	}
 
-		dayStart := time.Date(day.Year(), day.Month(), day.Day(), wsH, wsM, 0, 0, loc)
-		dayEnd := time.Date(day.Year(), day.Month(), day.Day(), weH, weM, 0, 0, loc)
+	// Fixed personal blocks such as lunch are busy like calendar events.
+	busyAll = append(busyAll, exclusionIntervals(exclusions, startDate, endDate, loc)...)
 
-		out := findFreeSlots(dayStart, dayEnd, busyAll, minDur)
-		if len(out) == 0 {
-			continue
-		}
-		fmt.Printf("- %s（%s） %s\n", day.Format("2006-01-02"), formatJpWeekday(day), strings.Join(out, ", "))
+	// Iterate working days and collect free slots
+	slotOpts := slotOptions{
+		minDur:      time.Duration(cfg.minMinutes) * time.Minute,
+		granularity: cfg.granularity,
+	}
+	meetingDur := time.Duration(cfg.durationMinutes) * time.Minute
+	if meetingDur > 0 {
+		slotOpts.minDur = meetingDur
+	}
+
+	// Working time is intersected on one timeline, as participants may work
+	// in other timezones.
+	timeMin, timeMax := queryRange(startDate, endDate, loc)
+	working := own.workingIntervals(timeMin, timeMax)
+	for _, p := range participants {
+		working = intersectIntervals(working, p.schedule.workingIntervals(timeMin, timeMax))
+	}
+	working = remainingIntervals(working, timeMin, timeMax)
+
+	days := freeDays(working, busyAll, startDate, endDate, loc, slotOpts,
+		meetingDur, time.Duration(cfg.stepMinutes)*time.Minute, cfg.maxPerDay)
+
+	outOpts := outputOptions{loc: loc, lang: lang, displayZones: displayZones}
+	if tmpl != nil {
+		err = writeTemplate(os.Stdout, tmpl, days, outOpts)
+	} else {
+		err = writeOutput(os.Stdout, cfg.format, days, outOpts)
+	}
+	if err != nil {
+		log.Fatalf("failed to write output: %v", err)
 	}
 }
@@ -4,6 +4,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
 )
 
 func TestMustParseClock(t *testing.T) {
@@ -385,6 +387,590 @@ func TestMergeIntervals(t *testing.T) {
 	}
 }
 
+func TestFindFreeSlots(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	parseTime := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return t
+	}
+
+	dayStart := parseTime("2025-01-13 09:00")
+	dayEnd := parseTime("2025-01-13 17:00")
+
+	tests := []struct {
+		name   string
+		busy   []interval
+		minDur time.Duration
+		want   []interval
+	}{
+		{
+			name:   "no events",
+			minDur: time.Hour,
+			want:   []interval{{start: dayStart, end: dayEnd}},
+		},
+		{
+			name: "events split the day",
+			busy: []interval{
+				{start: parseTime("2025-01-13 10:00"), end: parseTime("2025-01-13 11:00")},
+				{start: parseTime("2025-01-13 13:00"), end: parseTime("2025-01-13 16:30")},
+			},
+			minDur: 30 * time.Minute,
+			want: []interval{
+				{start: parseTime("2025-01-13 09:00"), end: parseTime("2025-01-13 10:00")},
+				{start: parseTime("2025-01-13 11:00"), end: parseTime("2025-01-13 13:00")},
+				{start: parseTime("2025-01-13 16:30"), end: parseTime("2025-01-13 17:00")},
+			},
+		},
+		{
+			name: "short gaps are dropped",
+			busy: []interval{
+				{start: parseTime("2025-01-13 09:30"), end: parseTime("2025-01-13 16:00")},
+			},
+			minDur: time.Hour,
+			want: []interval{
+				{start: parseTime("2025-01-13 16:00"), end: parseTime("2025-01-13 17:00")},
+			},
+		},
+		{
+			name: "events outside the day are ignored",
+			busy: []interval{
+				{start: parseTime("2025-01-12 09:00"), end: parseTime("2025-01-12 17:00")},
+			},
+			minDur: time.Hour,
+			want:   []interval{{start: dayStart, end: dayEnd}},
+		},
+		{
+			name: "fully booked",
+			busy: []interval{
+				{start: parseTime("2025-01-13 00:00"), end: parseTime("2025-01-14 00:00")},
+			},
+			minDur: time.Hour,
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findFreeSlots(dayStart, dayEnd, tt.busy, slotOptions{minDur: tt.minDur})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findFreeSlots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindFreeSlotsGranularity(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	parseTime := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return t
+	}
+
+	busy := []interval{
+		{start: parseTime("2025-01-13 09:00"), end: parseTime("2025-01-13 10:50")},
+		{start: parseTime("2025-01-13 12:10"), end: parseTime("2025-01-13 13:05")},
+		{start: parseTime("2025-01-13 14:20"), end: parseTime("2025-01-13 16:40")},
+	}
+
+	tests := []struct {
+		name string
+		opts slotOptions
+		want []string
+	}{
+		{
+			name: "no granularity",
+			opts: slotOptions{minDur: time.Hour},
+			want: []string{"10:50~12:10", "13:05~14:20"},
+		},
+		{
+			name: "15 minutes",
+			opts: slotOptions{minDur: time.Hour, granularity: 15 * time.Minute},
+			want: []string{"11:00~12:00", "13:15~14:15"},
+		},
+		{
+			name: "30 minutes drops slots that become too short",
+			opts: slotOptions{minDur: time.Hour, granularity: 30 * time.Minute},
+			want: []string{"11:00~12:00"},
+		},
+		{
+			name: "gaps shorter than the grid disappear",
+			opts: slotOptions{granularity: time.Hour},
+			want: []string{"11:00~12:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findFreeSlots(parseTime("2025-01-13 09:00"), parseTime("2025-01-13 17:00"), busy, tt.opts)
+			var gotStr []string
+			for _, s := range got {
+				gotStr = append(gotStr, formatSlot(s))
+			}
+			if !reflect.DeepEqual(gotStr, tt.want) {
+				t.Errorf("findFreeSlots() = %v, want %v", gotStr, tt.want)
+			}
+		})
+	}
+}
+
+func TestFreeDaysAcrossMidnight(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	london, _ := time.LoadLocation("Europe/London")
+	newYork, _ := time.LoadLocation("America/New_York")
+	monFri := weekdaySet{false, true, true, true, true, true, false}
+	everyDay := weekdaySet{true, true, true, true, true, true, true}
+	schedule := func(loc *time.Location, days weekdaySet, start, end int) workSchedule {
+		h, _ := parseWeeklyHours("", clockWindow{start: start * 60, end: end * 60})
+		return workSchedule{loc: loc, workdays: days, hours: h, isHoliday: holidayCalendars["none"]}
+	}
+	startDate := time.Date(2025, 1, 13, 0, 0, 0, 0, tokyo) // Monday
+	endDate := startDate.AddDate(0, 0, 1)
+	timeMin, timeMax := queryRange(startDate, endDate, tokyo)
+
+	// 09:00-17:00 in London and New York overlap 23:00-02:00 in Tokyo
+	working := schedule(tokyo, everyDay, 0, 24).workingIntervals(timeMin, timeMax)
+	for _, o := range []workSchedule{schedule(london, monFri, 9, 17), schedule(newYork, monFri, 9, 17)} {
+		working = intersectIntervals(working, o.workingIntervals(timeMin, timeMax))
+	}
+
+	at := func(day, h int) time.Time { return time.Date(2025, 1, day, h, 0, 0, 0, tokyo) }
+	got := freeDays(working, nil, startDate, endDate, tokyo, slotOptions{minDur: 2 * time.Hour}, 0, 0, 0)
+	// the 14th ends at the end of the range, an hour after 23:00
+	if len(got) != 1 || !got[0].date.Equal(at(13, 0)) || len(got[0].slots) != 1 {
+		t.Fatalf("freeDays() = %v, want a single slot on the 13th", got)
+	}
+	if s := got[0].slots[0]; !s.start.Equal(at(13, 23)) || !s.end.Equal(at(14, 2)) {
+		t.Errorf("slot = %v, want 23:00~02:00", s)
+	}
+
+	// candidates are listed under the day they start on
+	got = freeDays(working, nil, startDate, endDate, tokyo, slotOptions{minDur: time.Hour}, time.Hour, time.Hour, 0)
+	var starts []string
+	for _, d := range got {
+		for _, s := range d.slots {
+			starts = append(starts, d.date.Format("01-02")+" "+s.start.In(tokyo).Format("01-02 15:04"))
+		}
+	}
+	want := []string{"01-13 01-13 23:00", "01-13 01-14 00:00", "01-13 01-14 01:00", "01-14 01-14 23:00"}
+	if !reflect.DeepEqual(starts, want) {
+		t.Errorf("candidates = %v, want %v", starts, want)
+	}
+}
+
+func TestCandidateSlots(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	parseTime := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return t
+	}
+
+	free := []interval{
+		{start: parseTime("2025-01-13 09:00"), end: parseTime("2025-01-13 11:00")},
+		{start: parseTime("2025-01-13 13:00"), end: parseTime("2025-01-13 13:45")},
+		{start: parseTime("2025-01-13 15:00"), end: parseTime("2025-01-13 16:30")},
+	}
+
+	tests := []struct {
+		name     string
+		dur      time.Duration
+		step     time.Duration
+		maxCount int
+		want     []string
+	}{
+		{
+			name: "60 minutes every 30 minutes",
+			dur:  time.Hour,
+			step: 30 * time.Minute,
+			want: []string{"09:00~10:00", "09:30~10:30", "10:00~11:00", "15:00~16:00", "15:30~16:30"},
+		},
+		{
+			name: "step equal to duration",
+			dur:  30 * time.Minute,
+			step: 30 * time.Minute,
+			want: []string{"09:00~09:30", "09:30~10:00", "10:00~10:30", "10:30~11:00", "13:00~13:30",
+				"15:00~15:30", "15:30~16:00", "16:00~16:30"},
+		},
+		{
+			name:     "capped",
+			dur:      time.Hour,
+			step:     30 * time.Minute,
+			maxCount: 2,
+			want:     []string{"09:00~10:00", "09:30~10:30"},
+		},
+		{
+			name: "longer than every slot",
+			dur:  3 * time.Hour,
+			step: 30 * time.Minute,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, s := range candidateSlots(free, tt.dur, tt.step, tt.maxCount) {
+				got = append(got, formatSlot(s))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidateSlots() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsPhysicalLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		want     bool
+	}{
+		{"", false},
+		{"https://zoom.us/j/123456789", false},
+		{"Google Meet", false},
+		{"Microsoft Teams Meeting", false},
+		{"オンライン", false},
+		{"Conference Room A", true},
+		{"東京都千代田区丸の内1-1-1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			if got := isPhysicalLocation(tt.location); got != tt.want {
+				t.Errorf("isPhysicalLocation(%q) = %v, want %v", tt.location, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventsToIntervalsBuffers(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	parseTime := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return t
+	}
+	timed := func(start, end, location string) *calendar.Event {
+		return &calendar.Event{
+			Location: location,
+			Start:    &calendar.EventDateTime{DateTime: parseTime(start).Format(time.RFC3339)},
+			End:      &calendar.EventDateTime{DateTime: parseTime(end).Format(time.RFC3339)},
+		}
+	}
+
+	events := []*calendar.Event{
+		timed("2025-01-13 10:00", "2025-01-13 11:00", "https://meet.google.com/abc-defg-hij"),
+		timed("2025-01-13 14:00", "2025-01-13 15:00", "Head office, 3F"),
+		{
+			Start: &calendar.EventDateTime{Date: "2025-01-14"},
+			End:   &calendar.EventDateTime{Date: "2025-01-15"},
+		},
+	}
+	opts := eventOptions{
+		bufferBefore:       5 * time.Minute,
+		bufferAfter:        10 * time.Minute,
+		onsiteBufferBefore: 30 * time.Minute,
+		onsiteBufferAfter:  45 * time.Minute,
+	}
+
+	got := eventsToIntervals(events, loc, opts)
+	want := []interval{
+		{start: parseTime("2025-01-13 09:55"), end: parseTime("2025-01-13 11:10")},
+		{start: parseTime("2025-01-13 13:30"), end: parseTime("2025-01-13 15:45")},
+		{start: parseTime("2025-01-14 00:00"), end: parseTime("2025-01-15 00:00")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("eventsToIntervals() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].start.Equal(want[i].start) || !got[i].end.Equal(want[i].end) {
+			t.Errorf("eventsToIntervals()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// -min applies to the gaps left after the buffers
+	slots := findFreeSlots(parseTime("2025-01-13 09:00"), parseTime("2025-01-13 17:00"), got, slotOptions{minDur: 90 * time.Minute})
+	wantSlots := []string{"11:10~13:30"}
+	if len(slots) != len(wantSlots) || formatSlot(slots[0]) != wantSlots[0] {
+		t.Errorf("findFreeSlots() = %v, want %v", slots, wantSlots)
+	}
+}
+
+func TestEventsToIntervalsRSVP(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	start := time.Date(2025, 1, 13, 10, 0, 0, 0, loc)
+	end := start.Add(time.Hour)
+
+	event := func(status, response string) *calendar.Event {
+		e := &calendar.Event{
+			Status: status,
+			Start:  &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:    &calendar.EventDateTime{DateTime: end.Format(time.RFC3339)},
+		}
+		if response != "" {
+			e.Attendees = []*calendar.EventAttendee{
+				{Email: "organizer@example.com", ResponseStatus: "accepted"},
+				{Email: "me@example.com", Self: true, ResponseStatus: response},
+			}
+		}
+		return e
+	}
+
+	tests := []struct {
+		name     string
+		event    *calendar.Event
+		opts     eventOptions
+		wantBusy bool
+		wantSoft bool
+	}{
+		{name: "no attendees", event: event("confirmed", ""), wantBusy: true},
+		{name: "accepted", event: event("confirmed", "accepted"), wantBusy: true},
+		{name: "declined", event: event("confirmed", "declined")},
+		{name: "cancelled", event: event("cancelled", "accepted")},
+		{
+			name:     "tentative is busy by default",
+			event:    event("confirmed", "tentative"),
+			opts:     eventOptions{needsAction: treatBusy, tentative: treatBusy},
+			wantBusy: true,
+		},
+		{
+			name:  "tentative as free",
+			event: event("confirmed", "tentative"),
+			opts:  eventOptions{needsAction: treatBusy, tentative: treatFree},
+		},
+		{
+			name:     "needs action as soft",
+			event:    event("confirmed", "needsAction"),
+			opts:     eventOptions{needsAction: treatSoft, tentative: treatBusy},
+			wantBusy: true,
+			wantSoft: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eventsToIntervals([]*calendar.Event{tt.event}, loc, tt.opts)
+			if !tt.wantBusy {
+				if len(got) != 0 {
+					t.Errorf("eventsToIntervals() = %v, want none", got)
+				}
+				return
+			}
+			if len(got) != 1 || got[0].soft != tt.wantSoft {
+				t.Fatalf("eventsToIntervals() = %v, want one interval with soft=%v", got, tt.wantSoft)
+			}
+		})
+	}
+
+	// soft intervals do not block slots but are reported separately
+	busy := []interval{{start: start, end: end, soft: true}}
+	window := interval{start: start.Add(-time.Hour), end: end.Add(time.Hour)}
+	if slots := findFreeSlots(window.start, window.end, busy, slotOptions{}); len(slots) != 1 {
+		t.Errorf("findFreeSlots() = %v, want the whole window", slots)
+	}
+	if soft := softOverlaps(busy, []interval{window}); len(soft) != 1 || formatSlot(soft[0]) != "10:00~11:00" {
+		t.Errorf("softOverlaps() = %v, want [10:00~11:00]", soft)
+	}
+}
+
+func TestEventsToIntervalsEventTypes(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	parseTime := func(s string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		return t
+	}
+	timed := func(eventType, start, end string) *calendar.Event {
+		return &calendar.Event{
+			EventType: eventType,
+			Start:     &calendar.EventDateTime{DateTime: parseTime(start).Format(time.RFC3339)},
+			End:       &calendar.EventDateTime{DateTime: parseTime(end).Format(time.RFC3339)},
+		}
+	}
+	opts := eventOptions{
+		bufferBefore: 10 * time.Minute,
+		bufferAfter:  10 * time.Minute,
+		focusTime:    treatSoft,
+	}
+
+	tests := []struct {
+		name  string
+		event *calendar.Event
+		want  []interval
+	}{
+		{
+			name:  "default",
+			event: timed("default", "2025-01-13 10:00", "2025-01-13 11:00"),
+			want:  []interval{{start: parseTime("2025-01-13 09:50"), end: parseTime("2025-01-13 11:10")}},
+		},
+		{
+			name: "working location all day",
+			event: &calendar.Event{
+				EventType: eventTypeWorkingLocation,
+				Summary:   "Working from home",
+				Start:     &calendar.EventDateTime{Date: "2025-01-13"},
+				End:       &calendar.EventDateTime{Date: "2025-01-14"},
+			},
+		},
+		{
+			name:  "out of office in the afternoon",
+			event: timed(eventTypeOutOfOffice, "2025-01-13 13:00", "2025-01-13 17:00"),
+			want:  []interval{{start: parseTime("2025-01-13 00:00"), end: parseTime("2025-01-14 00:00")}},
+		},
+		{
+			name:  "out of office over two days",
+			event: timed(eventTypeOutOfOffice, "2025-01-13 15:00", "2025-01-15 00:00"),
+			want:  []interval{{start: parseTime("2025-01-13 00:00"), end: parseTime("2025-01-15 00:00")}},
+		},
+		{
+			name:  "focus time",
+			event: timed(eventTypeFocusTime, "2025-01-13 14:00", "2025-01-13 16:00"),
+			want:  []interval{{start: parseTime("2025-01-13 13:50"), end: parseTime("2025-01-13 16:10"), soft: true}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := eventsToIntervals([]*calendar.Event{tt.event}, loc, opts)
+			if len(got) != len(tt.want) {
+				t.Fatalf("eventsToIntervals() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if !got[i].start.Equal(tt.want[i].start) || !got[i].end.Equal(tt.want[i].end) || got[i].soft != tt.want[i].soft {
+					t.Errorf("eventsToIntervals()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEventsToIntervalsAllDay(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	allDay := func(summary, eventType string) *calendar.Event {
+		return &calendar.Event{
+			Summary:   summary,
+			EventType: eventType,
+			Start:     &calendar.EventDateTime{Date: "2025-01-13"},
+			End:       &calendar.EventDateTime{Date: "2025-01-14"},
+		}
+	}
+	timed := &calendar.Event{
+		Summary: "Design review",
+		Start:   &calendar.EventDateTime{DateTime: "2025-01-13T10:00:00+09:00"},
+		End:     &calendar.EventDateTime{DateTime: "2025-01-13T11:00:00+09:00"},
+	}
+
+	tests := []struct {
+		name  string
+		event *calendar.Event
+		want  map[string]bool // mode -> blocks time
+	}{
+		{
+			name:  "banner",
+			event: allDay("Sprint 42", ""),
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: false, allDayOOOOnly: false},
+		},
+		{
+			name:  "birthday",
+			event: allDay("Birthday", "birthday"),
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: false, allDayOOOOnly: false},
+		},
+		{
+			name:  "out of office type",
+			event: allDay("Away", eventTypeOutOfOffice),
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: false, allDayOOOOnly: true},
+		},
+		{
+			name:  "paid leave title",
+			event: allDay("有給", ""),
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: false, allDayOOOOnly: true},
+		},
+		{
+			name:  "OOO title",
+			event: allDay("OOO - family trip", ""),
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: false, allDayOOOOnly: true},
+		},
+		{
+			name:  "word containing ooo",
+			event: allDay("Zoo outing", ""),
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: false, allDayOOOOnly: false},
+		},
+		{
+			name:  "timed events are not affected",
+			event: timed,
+			want:  map[string]bool{allDayBusy: true, allDayIgnore: true, allDayOOOOnly: true},
+		},
+	}
+	for _, tt := range tests {
+		for mode, want := range tt.want {
+			t.Run(tt.name+"/"+mode, func(t *testing.T) {
+				got := eventsToIntervals([]*calendar.Event{tt.event}, loc, eventOptions{allDay: mode})
+				if blocks := len(got) > 0; blocks != want {
+					t.Errorf("eventsToIntervals() = %v, want blocking %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestStringListSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		inputs []string
+		want   stringList
+	}{
+		{
+			name:   "single value",
+			inputs: []string{"primary"},
+			want:   stringList{"primary"},
+		},
+		{
+			name:   "comma-separated",
+			inputs: []string{"primary,alice@example.com, bob@example.com"},
+			want:   stringList{"primary", "alice@example.com", "bob@example.com"},
+		},
+		{
+			name:   "repeated",
+			inputs: []string{"primary", "alice@example.com"},
+			want:   stringList{"primary", "alice@example.com"},
+		},
+		{
+			name:   "empty entries are skipped",
+			inputs: []string{"primary,,", " "},
+			want:   stringList{"primary"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got stringList
+			for _, in := range tt.inputs {
+				if err := got.Set(in); err != nil {
+					t.Fatalf("Set(%q) error = %v", in, err)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stringList = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawListSet(t *testing.T) {
+	var got rawList
+	for _, in := range []string{"mon,wed,fri=09:30-09:45", "12:00-13:00"} {
+		if err := got.Set(in); err != nil {
+			t.Fatalf("Set(%q) error = %v", in, err)
+		}
+	}
+	if want := (rawList{"mon,wed,fri=09:30-09:45", "12:00-13:00"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("rawList = %v, want %v", got, want)
+	}
+	for _, spec := range got {
+		if _, err := parseExclusion(spec); err != nil {
+			t.Errorf("parseExclusion(%q) error = %v", spec, err)
+		}
+	}
+}
+
 func TestOpenBrowser(t *testing.T) {
 	// This test just ensures the function doesn't panic
 	// It won't actually open a browser in test environment
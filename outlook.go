@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+)
+
+const (
+	graphBaseURL = "https://graph.microsoft.com/v1.0"
+	// graphCalendarsScope is the delegated permission needed by getSchedule.
+	graphCalendarsScope = "https://graph.microsoft.com/Calendars.Read"
+)
+
+// Limits of getSchedule: at most 20 schedules and 62 days per request.
+const (
+	graphMaxSchedules = 20
+	graphMaxRange     = 62 * 24 * time.Hour
+)
+
+// graphDateTimeFormat is the layout of dateTimeTimeZone values returned by
+// Microsoft Graph, e.g. "2025-01-13T01:00:00.0000000".
+const graphDateTimeFormat = "2006-01-02T15:04:05.9999999"
+
+// getMicrosoftClient authenticates with the Microsoft identity platform using
+// the same loopback flow as Google, and returns an HTTP client for Graph.
+func getMicrosoftClient(ctx context.Context, clientID, clientSecret, tenant, tokenPath string) (*http.Client, error) {
+	config := &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Endpoint:     microsoft.AzureADEndpoint(tenant),
+		Scopes:       []string{"offline_access", graphCalendarsScope},
+	}
+	ts, err := getTokenSource(ctx, config, tokenPath)
+	if err != nil {
+		return nil, err
+	}
+	return oauth2.NewClient(ctx, ts), nil
+}
+
+// graphSource returns the busy intervals of Microsoft 365 / Outlook
+// calendars using the getSchedule API of Microsoft Graph.
+type graphSource struct {
+	client    *http.Client
+	baseURL   string
+	schedules []string
+}
+
+func (s *graphSource) name() string {
+	return strings.Join(s.schedules, ",")
+}
+
+type graphDateTimeZone struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphScheduleRequest struct {
+	Schedules                []string          `json:"schedules"`
+	StartTime                graphDateTimeZone `json:"startTime"`
+	EndTime                  graphDateTimeZone `json:"endTime"`
+	AvailabilityViewInterval int               `json:"availabilityViewInterval"`
+}
+
+type graphScheduleResponse struct {
+	Value []struct {
+		ScheduleID string `json:"scheduleId"`
+		Error      *struct {
+			Message      string `json:"message"`
+			ResponseCode string `json:"responseCode"`
+		} `json:"error"`
+		ScheduleItems []struct {
+			Status string            `json:"status"`
+			Start  graphDateTimeZone `json:"start"`
+			End    graphDateTimeZone `json:"end"`
+		} `json:"scheduleItems"`
+	} `json:"value"`
+}
+
+func (s *graphSource) fetchBusy(
+	ctx context.Context,
+	startDate, endDate time.Time,
+	loc *time.Location,
+) ([]interval, error) {
+	timeMin, timeMax := queryRange(startDate, endDate, loc)
+
+	var busyAll []interval
+	for _, schedules := range chunkStrings(s.schedules, graphMaxSchedules) {
+		for _, r := range splitRange(timeMin, timeMax, graphMaxRange) {
+			resp, err := s.getSchedule(ctx, schedules, r)
+			if err != nil {
+				return nil, err
+			}
+			busy, err := graphScheduleToIntervals(resp, loc)
+			if err != nil {
+				return nil, err
+			}
+			busyAll = append(busyAll, busy...)
+		}
+	}
+	return busyAll, nil
+}
+
+func (s *graphSource) getSchedule(ctx context.Context, schedules []string, r interval) (*graphScheduleResponse, error) {
+	body, err := json.Marshal(graphScheduleRequest{
+		Schedules:                schedules,
+		StartTime:                graphDateTimeZone{DateTime: r.start.UTC().Format(graphDateTimeFormat), TimeZone: "UTC"},
+		EndTime:                  graphDateTimeZone{DateTime: r.end.UTC().Format(graphDateTimeFormat), TimeZone: "UTC"},
+		AvailabilityViewInterval: 15,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	baseURL := s.baseURL
+	if baseURL == "" {
+		baseURL = graphBaseURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/me/calendar/getSchedule", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// ask for times in UTC instead of the mailbox's (possibly Windows) zone
+	req.Header.Set("Prefer", `outlook.timezone="UTC"`)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("graph getSchedule: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var out graphScheduleResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("graph getSchedule: invalid response: %w", err)
+	}
+	return &out, nil
+}
+
+func graphScheduleToIntervals(resp *graphScheduleResponse, loc *time.Location) ([]interval, error) {
+	var busyAll []interval
+	for _, sch := range resp.Value {
+		if sch.Error != nil {
+			return nil, fmt.Errorf("graph schedule %s: %s", sch.ScheduleID, sch.Error.Message)
+		}
+		for _, item := range sch.ScheduleItems {
+			// free and workingElsewhere do not block time
+			switch item.Status {
+			case "busy", "tentative", "oof":
+			default:
+				continue
+			}
+			s, err1 := parseGraphDateTime(item.Start)
+			e, err2 := parseGraphDateTime(item.End)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			if !e.After(s) {
+				continue
+			}
+			busyAll = append(busyAll, interval{start: s.In(loc), end: e.In(loc)})
+		}
+	}
+	return busyAll, nil
+}
+
+func parseGraphDateTime(v graphDateTimeZone) (time.Time, error) {
+	zone := time.UTC
+	if v.TimeZone != "" && v.TimeZone != "UTC" {
+		if z, err := time.LoadLocation(v.TimeZone); err == nil {
+			zone = z
+		}
+	}
+	return time.ParseInLocation(graphDateTimeFormat, v.DateTime, zone)
+}
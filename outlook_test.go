@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func newGraphTestServer(t *testing.T, requests *int) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Method != http.MethodPost || r.URL.Path != "/me/calendar/getSchedule" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-token" {
+			t.Errorf("Authorization = %q, want %q", got, "Bearer test-token")
+		}
+		var req graphScheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		if len(req.Schedules) > graphMaxSchedules {
+			t.Errorf("request has %d schedules, want <= %d", len(req.Schedules), graphMaxSchedules)
+		}
+		if req.StartTime.DateTime != "2025-01-12T15:00:00" || req.StartTime.TimeZone != "UTC" {
+			t.Errorf("startTime = %+v", req.StartTime)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"value":[`)
+		for i, id := range req.Schedules {
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, `{"scheduleId":%q,"scheduleItems":[`+
+				`{"status":"busy","start":{"dateTime":"2025-01-13T01:00:00.0000000","timeZone":"UTC"},`+
+				`"end":{"dateTime":"2025-01-13T02:00:00.0000000","timeZone":"UTC"}},`+
+				`{"status":"workingElsewhere","start":{"dateTime":"2025-01-13T00:00:00.0000000","timeZone":"UTC"},`+
+				`"end":{"dateTime":"2025-01-13T09:00:00.0000000","timeZone":"UTC"}},`+
+				`{"status":"tentative","start":{"dateTime":"2025-01-13T05:30:00.0000000","timeZone":"UTC"},`+
+				`"end":{"dateTime":"2025-01-13T06:00:00.0000000","timeZone":"UTC"}}]}`, id)
+		}
+		io.WriteString(w, `]}`)
+	}))
+}
+
+func newGraphTestClient() *http.Client {
+	return oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "test-token"}))
+}
+
+func TestGraphSource(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	day := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+
+	requests := 0
+	srv := newGraphTestServer(t, &requests)
+	defer srv.Close()
+
+	src := &graphSource{client: newGraphTestClient(), baseURL: srv.URL, schedules: []string{"carol@example.com"}}
+	busy, err := src.fetchBusy(context.Background(), day, day, loc)
+	if err != nil {
+		t.Fatalf("fetchBusy() error = %v", err)
+	}
+
+	// mix with events from another source
+	events, err := (&icsFileSource{path: "testdata/calendar.ics"}).fetchEvents(context.Background(), day, day, loc)
+	if err != nil {
+		t.Fatalf("fetchEvents() error = %v", err)
+	}
+	busy = append(busy, eventsToIntervals(events, loc, eventOptions{})...)
+
+	got := findFreeSlots(
+		time.Date(2025, 1, 13, 9, 0, 0, 0, loc),
+		time.Date(2025, 1, 13, 17, 0, 0, 0, loc),
+		busy, slotOptions{minDur: 30 * time.Minute})
+	want := []string{"09:00~10:00", "11:30~14:30", "15:00~17:00"}
+	if len(got) != len(want) {
+		t.Fatalf("findFreeSlots() = %v, want %v", got, want)
+	}
+	for i, s := range got {
+		if formatSlot(s) != want[i] {
+			t.Errorf("slot %d = %s, want %s", i, formatSlot(s), want[i])
+		}
+	}
+}
+
+func TestGraphSourceBatching(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	day := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+
+	requests := 0
+	srv := newGraphTestServer(t, &requests)
+	defer srv.Close()
+
+	schedules := make([]string, graphMaxSchedules+5)
+	for i := range schedules {
+		schedules[i] = fmt.Sprintf("user%d@example.com", i)
+	}
+	src := &graphSource{client: newGraphTestClient(), baseURL: srv.URL, schedules: schedules}
+	busy, err := src.fetchBusy(context.Background(), day, day, loc)
+	if err != nil {
+		t.Fatalf("fetchBusy() error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, want 2", requests)
+	}
+	if len(busy) != 2*len(schedules) {
+		t.Errorf("got %d busy intervals, want %d", len(busy), 2*len(schedules))
+	}
+}
+
+func TestGraphScheduleError(t *testing.T) {
+	var resp graphScheduleResponse
+	body := `{"value":[{"scheduleId":"nobody@example.com","error":{"message":"not found","responseCode":"ErrorMailRecipientNotFound"}}]}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if _, err := graphScheduleToIntervals(&resp, time.UTC); err == nil {
+		t.Error("graphScheduleToIntervals() error = nil, want error")
+	}
+}
+
+func TestGetTokenSourceRefreshesExpiredToken(t *testing.T) {
+	refreshes := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "refresh_token" ||
+			r.Form.Get("refresh_token") != "refresh-1" {
+			http.Error(w, "unexpected token request", http.StatusBadRequest)
+			return
+		}
+		refreshes++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"access-2","token_type":"Bearer","refresh_token":"refresh-2","expires_in":3600}`)
+	}))
+	defer srv.Close()
+
+	tokenPath := filepath.Join(t.TempDir(), "ms_token.json")
+	expired := &oauth2.Token{
+		AccessToken:  "access-1",
+		TokenType:    "Bearer",
+		RefreshToken: "refresh-1",
+		Expiry:       time.Now().Add(-time.Hour),
+	}
+	if err := saveToken(tokenPath, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &oauth2.Config{ClientID: "client", Endpoint: oauth2.Endpoint{TokenURL: srv.URL}}
+	ts, err := getTokenSource(context.Background(), config, tokenPath)
+	if err != nil {
+		t.Fatalf("getTokenSource() error = %v", err)
+	}
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if tok.AccessToken != "access-2" || refreshes != 1 {
+		t.Errorf("Token() = %q after %d refreshes, want access-2 after 1", tok.AccessToken, refreshes)
+	}
+
+	b, err := os.ReadFile(tokenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved oauth2.Token
+	if err := json.Unmarshal(b, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if saved.AccessToken != "access-2" || saved.RefreshToken != "refresh-2" {
+		t.Errorf("saved token = %q/%q, want access-2/refresh-2", saved.AccessToken, saved.RefreshToken)
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+	formatICS      = "ics"
+	formatCSV      = "csv"
+	formatTable    = "table"
+	formatSlack    = "slack"
+	formatHTML     = "html"
+)
+
+// daySlots holds the free slots found on a single day.
+type daySlots struct {
+	date  time.Time
+	slots []interval
+	// soft is the soft busy time in the working hours, shown as a hint
+	// next to the free slots (see -tentative and -needs-action).
+	soft []interval
+}
+
+// outputOptions are the settings shared by all output formats.
+type outputOptions struct {
+	// loc is the timezone the slots are shown in.
+	loc *time.Location
+	// lang is the language of human-readable formats.
+	lang *locale
+	// displayZones are additional timezones every slot is shown in.
+	displayZones []*time.Location
+}
+
+// formatter writes the free slots in one output format.
+type formatter interface {
+	write(w io.Writer, days []daySlots, opts outputOptions) error
+}
+
+// formatters are the output formats selectable with -format.
+var formatters = map[string]formatter{
+	formatMarkdown: markdownFormatter{},
+	formatJSON:     jsonFormatter{},
+	formatICS:      icsFormatter{},
+	formatCSV:      csvFormatter{},
+	formatTable:    tableFormatter{},
+	formatSlack:    slackFormatter{},
+	formatHTML:     htmlFormatter{},
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(formatters))
+	for n := range formatters {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func isValidFormat(format string) bool {
+	_, ok := formatters[format]
+	return ok
+}
+
+func writeOutput(w io.Writer, format string, days []daySlots, opts outputOptions) error {
+	f, ok := formatters[format]
+	if !ok {
+		return fmt.Errorf("unknown format %q (want %s)", format, formatNames())
+	}
+	return f.write(w, daysInLocation(days, opts.loc), opts)
+}
+
+// daysInLocation returns days with every slot in loc, as slots can end at the
+// bounds of events or working hours in other timezones and formatters print
+// their clock times as they are.
+func daysInLocation(days []daySlots, loc *time.Location) []daySlots {
+	in := func(ss []interval) []interval {
+		if ss == nil {
+			return nil
+		}
+		out := make([]interval, 0, len(ss))
+		for _, s := range ss {
+			out = append(out, interval{start: s.start.In(loc), end: s.end.In(loc), soft: s.soft})
+		}
+		return out
+	}
+	out := make([]daySlots, 0, len(days))
+	for _, d := range days {
+		out = append(out, daySlots{date: d.date, slots: in(d.slots), soft: in(d.soft)})
+	}
+	return out
+}
+
+// formatSlot formats a slot as "HH:MM~HH:MM".
+func formatSlot(s interval) string {
+	return localeJa.slot(s)
+}
+
+// markdownFormatter writes a bullet per day, e.g.
+// "- 2025-01-13（月） 09:00~10:00, 14:00~15:30".
+type markdownFormatter struct{}
+
+func (markdownFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	lang := opts.lang
+	if len(days) == 0 {
+		_, err := fmt.Fprintln(w, lang.noSlots)
+		return err
+	}
+	for _, d := range days {
+		line := lang.slots(d.slots)
+		if len(d.soft) > 0 {
+			line += fmt.Sprintf(lang.softFormat, lang.slots(d.soft))
+		}
+		if _, err := fmt.Fprintf(w, "- %s%s%s\n", lang.day(d.date), lang.headingSep, line); err != nil {
+			return err
+		}
+		for _, zone := range opts.displayZones {
+			if _, err := fmt.Fprintf(w, "  - %s\n", lang.zonedSlots(convertSlots(d.slots, d.date, zone))); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// JSON output schema. Field names are part of the public interface used by
+// scripts, so only add new fields and never rename existing ones.
+type jsonOutput struct {
+	Days []jsonDay `json:"days"`
+}
+
+type jsonDay struct {
+	Date    string     `json:"date"`
+	Weekday string     `json:"weekday"`
+	Slots   []jsonSlot `json:"slots"`
+	// SoftBusy lists tentative or unanswered events that were not treated
+	// as busy.
+	SoftBusy []jsonBusy `json:"soft_busy,omitempty"`
+}
+
+type jsonBusy struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+type jsonSlot struct {
+	Start           string `json:"start"`
+	End             string `json:"end"`
+	DurationMinutes int    `json:"duration_minutes"`
+	Timezone        string `json:"timezone"`
+	// Display is the slot in each of the -display-tz timezones.
+	Display []jsonZonedSlot `json:"display,omitempty"`
+}
+
+type jsonZonedSlot struct {
+	Timezone     string `json:"timezone"`
+	Abbreviation string `json:"abbreviation"`
+	UTCOffset    string `json:"utc_offset"`
+	Start        string `json:"start"`
+	End          string `json:"end"`
+}
+
+// jsonFormatter writes the slots in the schema of jsonOutput.
+type jsonFormatter struct{}
+
+func (jsonFormatter) write(w io.Writer, days []daySlots, opts outputOptions) error {
+	loc := opts.loc
+	out := jsonOutput{Days: make([]jsonDay, 0, len(days))}
+	for _, d := range days {
+		jd := jsonDay{
+			Date:    d.date.Format("2006-01-02"),
+			Weekday: d.date.Weekday().String(),
+			Slots:   make([]jsonSlot, 0, len(d.slots)),
+		}
+		for _, s := range d.slots {
+			js := jsonSlot{
+				Start:           s.start.In(loc).Format(time.RFC3339),
+				End:             s.end.In(loc).Format(time.RFC3339),
+				DurationMinutes: int(s.end.Sub(s.start) / time.Minute),
+				Timezone:        loc.String(),
+			}
+			for _, zone := range opts.displayZones {
+				z := convertSlot(s, d.date, zone)
+				js.Display = append(js.Display, jsonZonedSlot{
+					Timezone:     zone.String(),
+					Abbreviation: z.abbr,
+					UTCOffset:    z.offset,
+					Start:        z.start.Format(time.RFC3339),
+					End:          z.end.Format(time.RFC3339),
+				})
+			}
+			jd.Slots = append(jd.Slots, js)
+		}
+		for _, s := range d.soft {
+			jd.SoftBusy = append(jd.SoftBusy, jsonBusy{
+				Start: s.start.In(loc).Format(time.RFC3339),
+				End:   s.end.In(loc).Format(time.RFC3339),
+			})
+		}
+		out.Days = append(out.Days, jd)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
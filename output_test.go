@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata/golden")
+
+func testDays(loc *time.Location) []daySlots {
+	at := func(day, h, m int) time.Time {
+		return time.Date(2025, 1, day, h, m, 0, 0, loc)
+	}
+	return []daySlots{
+		{
+			date: at(13, 0, 0),
+			slots: []interval{
+				{start: at(13, 9, 0), end: at(13, 10, 0)},
+				{start: at(13, 14, 0), end: at(13, 15, 30)},
+			},
+		},
+		{
+			date:  at(14, 0, 0),
+			slots: []interval{{start: at(14, 10, 30), end: at(14, 12, 0)}},
+		},
+	}
+}
+
+// TestFormattersGolden compares every format with testdata/golden/<format>.golden.
+// Run "go test -run TestFormattersGolden -update" to regenerate them.
+func TestFormattersGolden(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	defer func(orig func() time.Time) { now = orig }(now)
+	now = func() time.Time { return time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC) }
+
+	days := testDays(loc)
+	days[1].soft = []interval{{
+		start: time.Date(2025, 1, 14, 13, 0, 0, 0, loc),
+		end:   time.Date(2025, 1, 14, 14, 0, 0, 0, loc),
+	}}
+
+	for name := range formatters {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeOutput(&buf, name, days, outputOptions{loc: loc, lang: &localeJa}); err != nil {
+				t.Fatalf("writeOutput() error = %v", err)
+			}
+			path := filepath.Join("testdata", "golden", name+".golden")
+			if *update {
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("%v (run with -update to create it)", err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("writeOutput(%s) =\n%s\nwant\n%s", name, got, want)
+			}
+		})
+	}
+}
+
+func TestWriteOutputUnknownFormat(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, "pdf", testDays(loc), outputOptions{loc: loc, lang: &localeJa}); err == nil {
+		t.Error("writeOutput(pdf) succeeded")
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "Mon", want: 3},
+		{s: "月", want: 2},
+		{s: "2025-01-13（月）", want: 16},
+	}
+	for _, tt := range tests {
+		if got := displayWidth(tt.s); got != tt.want {
+			t.Errorf("displayWidth(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, formatMarkdown, testDays(loc), outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	want := "- 2025-01-13（月） 09:00~10:00, 14:00~15:30\n" +
+		"- 2025-01-14（火） 10:30~12:00\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeOutput() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteOutputConvertsToTZ(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	london, _ := time.LoadLocation("Europe/London")
+	// 18:00~19:00 in Tokyo, bounded by working hours in London
+	days := []daySlots{{
+		date: time.Date(2025, 1, 13, 0, 0, 0, 0, loc),
+		slots: []interval{{
+			start: time.Date(2025, 1, 13, 9, 0, 0, 0, london),
+			end:   time.Date(2025, 1, 13, 19, 0, 0, 0, loc),
+		}},
+	}}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: formatMarkdown, want: "18:00~19:00"},
+		{format: formatSlack, want: "18:00~19:00"},
+		{format: formatHTML, want: "18:00~19:00"},
+		{format: formatCSV, want: "18:00,19:00,60"},
+		{format: formatTable, want: "18:00  19:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := writeOutput(&buf, tt.format, days, outputOptions{loc: loc, lang: &localeJa}); err != nil {
+				t.Fatalf("writeOutput() error = %v", err)
+			}
+			if got := buf.String(); !strings.Contains(got, tt.want) {
+				t.Errorf("writeOutput(%s) =\n%s\nwant to contain %q", tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteSoftBusy(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	days := testDays(loc)[1:]
+	days[0].soft = []interval{{
+		start: time.Date(2025, 1, 14, 13, 0, 0, 0, loc),
+		end:   time.Date(2025, 1, 14, 14, 0, 0, 0, loc),
+	}}
+
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, formatMarkdown, days, outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	if got, want := buf.String(), "- 2025-01-14（火） 10:30~12:00（仮予定: 13:00~14:00）\n"; got != want {
+		t.Errorf("writeOutput(markdown) = %q, want %q", got, want)
+	}
+
+	buf.Reset()
+	if err := writeOutput(&buf, formatJSON, days, outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	want := `"soft_busy": [
+        {
+          "start": "2025-01-14T13:00:00+09:00",
+          "end": "2025-01-14T14:00:00+09:00"
+        }
+      ]`
+	if got := buf.String(); !strings.Contains(got, want) {
+		t.Errorf("writeOutput(json) =\n%s\nwant to contain\n%s", got, want)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, formatJSON, testDays(loc)[1:], outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	want := `{
+  "days": [
+    {
+      "date": "2025-01-14",
+      "weekday": "Tuesday",
+      "slots": [
+        {
+          "start": "2025-01-14T10:30:00+09:00",
+          "end": "2025-01-14T12:00:00+09:00",
+          "duration_minutes": 90,
+          "timezone": "Asia/Tokyo"
+        }
+      ]
+    }
+  ]
+}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("writeOutput() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteJSONEmpty(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	var buf bytes.Buffer
+	if err := writeOutput(&buf, formatJSON, nil, outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeOutput() error = %v", err)
+	}
+	if got, want := buf.String(), "{\n  \"days\": []\n}\n"; got != want {
+		t.Errorf("writeOutput() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rrule is the subset of RFC 5545 recurrence rules found in calendar exports:
+// FREQ (DAILY, WEEKLY, MONTHLY, YEARLY) with INTERVAL, COUNT, UNTIL, BYDAY,
+// BYMONTHDAY and BYMONTH in any combination RFC 5545 allows. parseRRule
+// rejects other parts (BYSETPOS, BYWEEKNO, ...), so that no rule is expanded
+// into the wrong instances.
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []rruleWeekday
+	byMonthDay []int
+	byMonth    []time.Month
+}
+
+// rruleWeekday is a BYDAY entry such as "MO" (n == 0) or "-1FR".
+type rruleWeekday struct {
+	n       int
+	weekday time.Weekday
+}
+
+// rruleMaxPeriods bounds the expansion of rules that never produce an
+// instance, e.g. "every February 30th".
+const rruleMaxPeriods = 100000
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// parseRRule parses the value of an RRULE property. Floating UNTIL values
+// are read in loc, the location of the event's DTSTART.
+func parseRRule(v string, loc *time.Location) (*rrule, error) {
+	r := &rrule{interval: 1}
+	for _, part := range strings.Split(v, ";") {
+		k, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(k) {
+		case "FREQ":
+			r.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(val)
+			if err == nil && r.interval < 1 {
+				err = fmt.Errorf("invalid INTERVAL %q", val)
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(val)
+		case "UNTIL":
+			r.until, err = parseRRuleUntil(val, loc)
+		case "BYDAY":
+			r.byDay, err = parseRRuleByDay(val)
+		case "BYMONTHDAY":
+			for _, s := range strings.Split(val, ",") {
+				var d int
+				if d, err = strconv.Atoi(s); err != nil {
+					break
+				}
+				if d == 0 || d < -31 || d > 31 {
+					err = fmt.Errorf("invalid day %d", d)
+					break
+				}
+				r.byMonthDay = append(r.byMonthDay, d)
+			}
+		case "BYMONTH":
+			for _, s := range strings.Split(val, ",") {
+				var m int
+				if m, err = strconv.Atoi(s); err != nil {
+					break
+				}
+				if m < 1 || m > 12 {
+					err = fmt.Errorf("invalid month %d", m)
+					break
+				}
+				r.byMonth = append(r.byMonth, time.Month(m))
+			}
+		case "WKST":
+			// the week start only changes weekly rules with BYDAY and an
+			// INTERVAL, which are expanded with weeks starting on Monday
+			if !strings.EqualFold(val, "MO") {
+				return nil, fmt.Errorf("unsupported RRULE WKST %q", val)
+			}
+		default:
+			// BYSETPOS, BYWEEKNO, BYYEARDAY, BYHOUR, ...
+			return nil, fmt.Errorf("unsupported RRULE part %s", strings.ToUpper(k))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid RRULE %s: %w", k, err)
+		}
+	}
+
+	switch r.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported RRULE FREQ %q", r.freq)
+	}
+	// combinations RFC 5545 does not define
+	if r.freq == "WEEKLY" && len(r.byMonthDay) > 0 {
+		return nil, fmt.Errorf("unsupported RRULE BYMONTHDAY in a WEEKLY rule")
+	}
+	if r.freq == "DAILY" || r.freq == "WEEKLY" {
+		for _, wd := range r.byDay {
+			if wd.n != 0 {
+				return nil, fmt.Errorf("unsupported RRULE BYDAY position in a %s rule", r.freq)
+			}
+		}
+	}
+	return r, nil
+}
+
+func parseRRuleUntil(v string, loc *time.Location) (time.Time, error) {
+	switch {
+	case len(v) == len("20060102"):
+		// a date UNTIL includes every instance on that day
+		d, err := time.ParseInLocation("20060102", v, loc)
+		return d.AddDate(0, 0, 1).Add(-time.Nanosecond), err
+	case strings.HasSuffix(v, "Z"):
+		return time.Parse(icsUTCDateTimeFormat, v)
+	default:
+		return time.ParseInLocation(icsDateTimeFormat, v, loc)
+	}
+}
+
+func parseRRuleByDay(v string) ([]rruleWeekday, error) {
+	var out []rruleWeekday
+	for _, s := range strings.Split(strings.ToUpper(v), ",") {
+		if len(s) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		wd, ok := rruleWeekdays[s[len(s)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		n := 0
+		if prefix := s[:len(s)-2]; prefix != "" {
+			var err error
+			if n, err = strconv.Atoi(prefix); err != nil {
+				return nil, fmt.Errorf("invalid BYDAY %q", s)
+			}
+		}
+		out = append(out, rruleWeekday{n: n, weekday: wd})
+	}
+	return out, nil
+}
+
+// occurrences returns the start times of the instances of the rule that
+// begin at or after dtstart and before limit. COUNT is applied from dtstart
+// regardless of limit.
+func (r *rrule) occurrences(dtstart, limit time.Time) []time.Time {
+	var out []time.Time
+	n := 0
+	for period := 0; period < rruleMaxPeriods; period++ {
+		for _, c := range r.candidates(dtstart, period) {
+			if c.Before(dtstart) {
+				continue
+			}
+			if !c.Before(limit) || (!r.until.IsZero() && c.After(r.until)) {
+				return out
+			}
+			n++
+			if r.count > 0 && n > r.count {
+				return out
+			}
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// candidates returns the sorted instance starts of the period-th period
+// (day, week, month or year) after dtstart. BYxxx parts expand or limit the
+// instances of a period as in the table of RFC 5545 section 3.3.10.
+func (r *rrule) candidates(dtstart time.Time, period int) []time.Time {
+	step := period * r.interval
+	start := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 0, 0, 0, 0, time.UTC)
+
+	// dates as midnights in UTC, so that adding days is not affected by DST
+	var days []time.Time
+	switch r.freq {
+	case "DAILY":
+		days = []time.Time{start.AddDate(0, 0, step)}
+	case "WEEKLY":
+		// weeks start on Monday (the default WKST)
+		offset := (int(start.Weekday()) + 6) % 7
+		monday := start.AddDate(0, 0, 7*step-offset)
+		if len(r.byDay) == 0 {
+			days = append(days, monday.AddDate(0, 0, offset))
+		}
+		for _, wd := range r.byDay {
+			days = append(days, monday.AddDate(0, 0, (int(wd.weekday)+6)%7))
+		}
+	case "MONTHLY":
+		first := start.AddDate(0, step, 1-start.Day())
+		days = r.monthDays(first.Year(), first.Month(), start.Day())
+	case "YEARLY":
+		y := start.Year() + step
+		switch {
+		case len(r.byMonth) > 0:
+			for _, m := range r.byMonth {
+				days = append(days, r.monthDays(y, m, start.Day())...)
+			}
+		case len(r.byMonthDay) > 0 || len(r.byDay) > 0:
+			days = r.yearDays(y)
+		default:
+			days = r.monthDays(y, start.Month(), start.Day())
+		}
+	}
+
+	var out []time.Time
+	for _, d := range days {
+		// BYMONTH limits DAILY, WEEKLY and MONTHLY rules, and BYMONTHDAY and
+		// BYDAY limit DAILY rules
+		if len(r.byMonth) > 0 && !r.hasMonth(d.Month()) {
+			continue
+		}
+		if r.freq == "DAILY" && (!r.hasMonthDay(d) || len(r.byDay) > 0 && !r.hasWeekday(d.Weekday())) {
+			continue
+		}
+		out = append(out, time.Date(d.Year(), d.Month(), d.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location()))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	// e.g. BYMONTHDAY=1,-31 in January
+	return slices.CompactFunc(out, time.Time.Equal)
+}
+
+func (r *rrule) hasWeekday(wd time.Weekday) bool {
+	for _, x := range r.byDay {
+		if x.weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rrule) hasMonth(m time.Month) bool {
+	for _, x := range r.byMonth {
+		if x == m {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMonthDay reports whether BYMONTHDAY, if set, includes the date d.
+func (r *rrule) hasMonthDay(d time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	for _, md := range r.monthDayDates(d.Year(), d.Month()) {
+		if md.Equal(d) {
+			return true
+		}
+	}
+	return false
+}
+
+// monthDays returns the dates of the given month selected by BYMONTHDAY and
+// BYDAY, which limits BYMONTHDAY when both are set, or defaultDay when
+// neither is.
+func (r *rrule) monthDays(y int, m time.Month, defaultDay int) []time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	last := daysIn(y, m)
+	switch {
+	case len(r.byMonthDay) > 0 && len(r.byDay) > 0:
+		return intersectDates(r.monthDayDates(y, m), r.byDayDates(first, last))
+	case len(r.byMonthDay) > 0:
+		return r.monthDayDates(y, m)
+	case len(r.byDay) > 0:
+		return r.byDayDates(first, last)
+	case defaultDay <= last:
+		return []time.Time{first.AddDate(0, 0, defaultDay-1)}
+	default:
+		return nil
+	}
+}
+
+// yearDays returns the dates of a yearly rule without BYMONTH: BYMONTHDAY in
+// every month, limited by BYDAY, or BYDAY with positions counted in the
+// whole year (e.g. 20MO is the 20th Monday of the year).
+func (r *rrule) yearDays(y int) []time.Time {
+	first := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+	n := time.Date(y, time.December, 31, 0, 0, 0, 0, time.UTC).YearDay()
+	if len(r.byMonthDay) == 0 {
+		return r.byDayDates(first, n)
+	}
+	var days []time.Time
+	for m := time.January; m <= time.December; m++ {
+		days = append(days, r.monthDayDates(y, m)...)
+	}
+	if len(r.byDay) > 0 {
+		days = intersectDates(days, r.byDayDates(first, n))
+	}
+	return days
+}
+
+// monthDayDates returns the BYMONTHDAY dates of a month, counting negative
+// days from its end.
+func (r *rrule) monthDayDates(y int, m time.Month) []time.Time {
+	last := daysIn(y, m)
+	var out []time.Time
+	for _, d := range r.byMonthDay {
+		if d < 0 {
+			d = last + d + 1
+		}
+		if d >= 1 && d <= last {
+			out = append(out, time.Date(y, m, d, 0, 0, 0, 0, time.UTC))
+		}
+	}
+	return out
+}
+
+// byDayDates returns the BYDAY dates of the n days from first, counting
+// positions such as 2MO or -1FR within those days.
+func (r *rrule) byDayDates(first time.Time, n int) []time.Time {
+	var out []time.Time
+	for _, wd := range r.byDay {
+		var all []time.Time
+		for d := (int(wd.weekday) - int(first.Weekday()) + 7) % 7; d < n; d += 7 {
+			all = append(all, first.AddDate(0, 0, d))
+		}
+		switch {
+		case wd.n == 0:
+			out = append(out, all...)
+		case wd.n > 0 && wd.n <= len(all):
+			out = append(out, all[wd.n-1])
+		case wd.n < 0 && -wd.n <= len(all):
+			out = append(out, all[len(all)+wd.n])
+		}
+	}
+	return out
+}
+
+func intersectDates(a, b []time.Time) []time.Time {
+	var out []time.Time
+	for _, x := range a {
+		for _, y := range b {
+			if x.Equal(y) {
+				out = append(out, x)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
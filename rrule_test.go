@@ -0,0 +1,157 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRRuleOccurrences(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		limit   time.Time
+		want    []string
+	}{
+		{
+			name:    "daily with count",
+			rule:    "FREQ=DAILY;COUNT=3",
+			dtstart: time.Date(2025, 1, 13, 9, 0, 0, 0, loc),
+			limit:   time.Date(2025, 2, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-13 09:00", "2025-01-14 09:00", "2025-01-15 09:00"},
+		},
+		{
+			name:    "daily on weekdays",
+			rule:    "FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR",
+			dtstart: time.Date(2025, 1, 16, 9, 0, 0, 0, loc),
+			limit:   time.Date(2025, 1, 21, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-16 09:00", "2025-01-17 09:00", "2025-01-20 09:00"},
+		},
+		{
+			name:    "biweekly on two days until a date",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;UNTIL=20250130",
+			dtstart: time.Date(2025, 1, 14, 10, 0, 0, 0, loc),
+			limit:   time.Date(2025, 3, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-14 10:00", "2025-01-16 10:00", "2025-01-28 10:00", "2025-01-30 10:00"},
+		},
+		{
+			name:    "monthly on the last Friday",
+			rule:    "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			dtstart: time.Date(2025, 1, 31, 15, 0, 0, 0, loc),
+			limit:   time.Date(2026, 1, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-31 15:00", "2025-02-28 15:00", "2025-03-28 15:00"},
+		},
+		{
+			name:    "monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY",
+			dtstart: time.Date(2025, 1, 31, 9, 0, 0, 0, loc),
+			limit:   time.Date(2025, 6, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-31 09:00", "2025-03-31 09:00", "2025-05-31 09:00"},
+		},
+		{
+			name:    "yearly on the second Monday of January",
+			rule:    "FREQ=YEARLY;BYMONTH=1;BYDAY=2MO",
+			dtstart: time.Date(2025, 1, 13, 0, 0, 0, 0, loc),
+			limit:   time.Date(2027, 12, 31, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-13 00:00", "2026-01-12 00:00", "2027-01-11 00:00"},
+		},
+		{
+			name:    "daily limited to a month",
+			rule:    "FREQ=DAILY;BYMONTH=3;COUNT=3",
+			dtstart: time.Date(2025, 1, 13, 9, 0, 0, 0, loc),
+			limit:   time.Date(2026, 1, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-03-01 09:00", "2025-03-02 09:00", "2025-03-03 09:00"},
+		},
+		{
+			name:    "daily limited to days of the month",
+			rule:    "FREQ=DAILY;BYMONTHDAY=1,-1",
+			dtstart: time.Date(2025, 1, 13, 9, 0, 0, 0, loc),
+			limit:   time.Date(2025, 3, 2, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-31 09:00", "2025-02-01 09:00", "2025-02-28 09:00", "2025-03-01 09:00"},
+		},
+		{
+			name:    "weekly limited to a month",
+			rule:    "FREQ=WEEKLY;BYDAY=MO;BYMONTH=2",
+			dtstart: time.Date(2025, 1, 13, 9, 0, 0, 0, loc),
+			limit:   time.Date(2025, 12, 31, 0, 0, 0, 0, loc),
+			want:    []string{"2025-02-03 09:00", "2025-02-10 09:00", "2025-02-17 09:00", "2025-02-24 09:00"},
+		},
+		{
+			name:    "monthly limited to months",
+			rule:    "FREQ=MONTHLY;BYMONTH=1,7",
+			dtstart: time.Date(2025, 1, 15, 9, 0, 0, 0, loc),
+			limit:   time.Date(2026, 7, 16, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-15 09:00", "2025-07-15 09:00", "2026-01-15 09:00", "2026-07-15 09:00"},
+		},
+		{
+			name:    "monthly on Friday the 13th",
+			rule:    "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			dtstart: time.Date(2025, 1, 1, 0, 0, 0, 0, loc),
+			limit:   time.Date(2026, 12, 31, 0, 0, 0, 0, loc),
+			want:    []string{"2025-06-13 00:00", "2026-02-13 00:00", "2026-03-13 00:00", "2026-11-13 00:00"},
+		},
+		{
+			name:    "yearly on the 20th Monday of the year",
+			rule:    "FREQ=YEARLY;BYDAY=20MO",
+			dtstart: time.Date(2025, 1, 1, 9, 0, 0, 0, loc),
+			limit:   time.Date(2027, 1, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-05-19 09:00", "2026-05-18 09:00"},
+		},
+		{
+			name:    "yearly on the first of every month",
+			rule:    "FREQ=YEARLY;BYMONTHDAY=1;COUNT=3",
+			dtstart: time.Date(2025, 1, 1, 9, 0, 0, 0, loc),
+			limit:   time.Date(2027, 1, 1, 0, 0, 0, 0, loc),
+			want:    []string{"2025-01-01 09:00", "2025-02-01 09:00", "2025-03-01 09:00"},
+		},
+		{
+			name:    "limit stops infinite rules",
+			rule:    "FREQ=WEEKLY",
+			dtstart: time.Date(2020, 1, 6, 9, 0, 0, 0, loc),
+			limit:   time.Date(2020, 1, 20, 9, 0, 0, 0, loc),
+			want:    []string{"2020-01-06 09:00", "2020-01-13 09:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := parseRRule(tt.rule, loc)
+			if err != nil {
+				t.Fatalf("parseRRule(%q) error = %v", tt.rule, err)
+			}
+			got := r.occurrences(tt.dtstart, tt.limit)
+			if len(got) != len(tt.want) {
+				t.Fatalf("occurrences() = %v, want %v", got, tt.want)
+			}
+			for i, g := range got {
+				if s := g.Format("2006-01-02 15:04"); s != tt.want[i] {
+					t.Errorf("occurrences()[%d] = %s, want %s", i, s, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRRuleErrors(t *testing.T) {
+	for _, rule := range []string{
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;COUNT",
+		"FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+		"FREQ=YEARLY;BYWEEKNO=20",
+		"FREQ=YEARLY;BYYEARDAY=100",
+		"FREQ=DAILY;BYHOUR=9,15",
+		"FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU;WKST=SU",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;BYDAY=1MO",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+	} {
+		if _, err := parseRRule(rule, time.UTC); err == nil {
+			t.Errorf("parseRRule(%q) error = nil, want error", rule)
+		}
+	}
+}
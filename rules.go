@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+// eventRule decides how matching events are treated before they become busy
+// intervals, e.g. to ignore optional watch parties. A rule is written as
+// key=value pairs separated by semicolons, and matches an event when all of
+// them match:
+//
+//	summary=^Optional:;color=8
+//
+// Keys are summary (a regular expression), color (colorId), type
+// (eventType), organizer (email address), visibility and calendar (the
+// calendar ID, file or URL the event was read from).
+type eventRule struct {
+	spec   string
+	action treatment
+
+	summary    *regexp.Regexp
+	colorID    string
+	eventType  string
+	organizer  string
+	visibility string
+	calendar   string
+}
+
+func parseEventRule(spec string, action treatment) (eventRule, error) {
+	r := eventRule{spec: spec, action: action}
+	if strings.TrimSpace(spec) == "" {
+		return r, fmt.Errorf("empty rule")
+	}
+	for _, part := range strings.Split(spec, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("invalid condition %q in rule %q (want key=value)", part, spec)
+		}
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "summary":
+			re, err := regexp.Compile(value)
+			if err != nil {
+				return r, fmt.Errorf("invalid summary pattern in rule %q: %w", spec, err)
+			}
+			r.summary = re
+		case "color", "colorid":
+			r.colorID = strings.TrimSpace(value)
+		case "type", "eventtype":
+			r.eventType = strings.TrimSpace(value)
+		case "organizer":
+			r.organizer = strings.TrimSpace(value)
+		case "visibility":
+			r.visibility = strings.TrimSpace(value)
+		case "calendar":
+			r.calendar = strings.TrimSpace(value)
+		default:
+			return r, fmt.Errorf("unknown key %q in rule %q (want summary, color, type, organizer, visibility or calendar)", key, spec)
+		}
+	}
+	return r, nil
+}
+
+func (r eventRule) matches(source string, e *calendar.Event) bool {
+	if r.calendar != "" && !strings.EqualFold(source, r.calendar) {
+		return false
+	}
+	if r.summary != nil && !r.summary.MatchString(e.Summary) {
+		return false
+	}
+	if r.colorID != "" && e.ColorId != r.colorID {
+		return false
+	}
+	if r.eventType != "" && !strings.EqualFold(e.EventType, r.eventType) {
+		return false
+	}
+	if r.organizer != "" && (e.Organizer == nil || !strings.EqualFold(e.Organizer.Email, r.organizer)) {
+		return false
+	}
+	if r.visibility != "" {
+		// an unset visibility is the calendar's default
+		v := e.Visibility
+		if v == "" {
+			v = "default"
+		}
+		if !strings.EqualFold(v, r.visibility) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchRule returns the index of the first rule matching e read from
+// source, or -1. Events I declined match no rule, so that a -soft rule does
+// not bring them back.
+func matchRule(rules []eventRule, source string, e *calendar.Event) int {
+	if isDeclined(e) {
+		return -1
+	}
+	for i, r := range rules {
+		if r.matches(source, e) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ruleSpec is the value of an -ignore or -soft flag.
+type ruleSpec struct {
+	action treatment
+	spec   string
+}
+
+// ruleFlag is the flag.Value of -ignore (action treatFree) and -soft
+// (treatSoft). Both append to the same list, so that rules are checked in
+// the order they were given whichever flag they came from.
+type ruleFlag struct {
+	action treatment
+	specs  *[]ruleSpec
+}
+
+func (f *ruleFlag) String() string {
+	return strings.Join(f.values(), " ")
+}
+
+func (f *ruleFlag) Set(v string) error {
+	*f.specs = append(*f.specs, ruleSpec{action: f.action, spec: v})
+	return nil
+}
+
+// values returns the specs given with this flag, in order.
+func (f *ruleFlag) values() []string {
+	out := []string{}
+	if f.specs == nil {
+		return out
+	}
+	for _, s := range *f.specs {
+		if s.action == f.action {
+			out = append(out, s.spec)
+		}
+	}
+	return out
+}
+
+// sourceEvents are the events fetched from a single source.
+type sourceEvents struct {
+	source string
+	events []*calendar.Event
+}
+
+// writeRuleMatches lists the events each rule matched, for -dry-run. An event
+// is listed under the first rule it matches only, as that is the one applied.
+func writeRuleMatches(w io.Writer, rules []eventRule, fetched []sourceEvents, loc *time.Location) error {
+	for i, r := range rules {
+		if _, err := fmt.Fprintf(w, "%s %s\n", ruleActionName(r.action), r.spec); err != nil {
+			return err
+		}
+		n := 0
+		for _, f := range fetched {
+			for _, e := range f.events {
+				if matchRule(rules, f.source, e) != i {
+					continue
+				}
+				n++
+				if _, err := fmt.Fprintf(w, "  %s %s (%s)\n", formatEventStart(e, loc), e.Summary, f.source); err != nil {
+					return err
+				}
+			}
+		}
+		if n == 0 {
+			if _, err := fmt.Fprintln(w, "  (no events)"); err != nil {
+				return err
+			}
+		}
+		if i < len(rules)-1 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func ruleActionName(t treatment) string {
+	if t == treatFree {
+		return "ignore"
+	}
+	return string(t)
+}
+
+func formatEventStart(e *calendar.Event, loc *time.Location) string {
+	s, _, ok := parseEventTime(e, loc)
+	switch {
+	case !ok:
+		return "????-??-?? --:--"
+	case e.Start.DateTime == "":
+		return s.Format("2006-01-02") + " (all day)"
+	default:
+		return s.Format("2006-01-02 15:04")
+	}
+}
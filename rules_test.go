@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"testing"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+func TestParseEventRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "summary=^Optional:"},
+		{spec: "color=8"},
+		{spec: "summary=watch party, all hands;type=default;organizer=boss@example.com;visibility=private"},
+		{spec: "calendar=team@example.com;summary=."},
+		{spec: "summary=(unclosed", wantErr: true},
+		{spec: "room=A", wantErr: true},
+		{spec: "color", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := parseEventRule(tt.spec, treatFree)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseEventRule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEventRuleMatches(t *testing.T) {
+	event := &calendar.Event{
+		Summary:   "Optional: all-hands watch party",
+		ColorId:   "8",
+		EventType: "default",
+		Organizer: &calendar.EventOrganizer{Email: "ceo@example.com"},
+	}
+
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{spec: "summary=^Optional:", want: true},
+		{spec: "summary=^Sprint", want: false},
+		{spec: "color=8", want: true},
+		{spec: "color=3", want: false},
+		{spec: "type=default", want: true},
+		{spec: "type=focusTime", want: false},
+		{spec: "organizer=CEO@example.com", want: true},
+		{spec: "organizer=cto@example.com", want: false},
+		{spec: "visibility=default", want: true},
+		{spec: "visibility=private", want: false},
+		{spec: "summary=watch party;color=8", want: true},
+		{spec: "summary=watch party;color=3", want: false},
+		{spec: "calendar=primary;color=8", want: true},
+		{spec: "calendar=team@example.com;color=8", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			r, err := parseEventRule(tt.spec, treatFree)
+			if err != nil {
+				t.Fatalf("parseEventRule(%q) error = %v", tt.spec, err)
+			}
+			if got := r.matches("primary", event); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventsToIntervalsRules(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	timed := func(summary, colorID string, hour int) *calendar.Event {
+		start := time.Date(2025, 1, 13, hour, 0, 0, 0, loc)
+		return &calendar.Event{
+			Summary: summary,
+			ColorId: colorID,
+			Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:     &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+		}
+	}
+	events := []*calendar.Event{
+		timed("Optional: all-hands watch party", "", 10),
+		timed("1on1", "8", 11),
+		timed("Design review", "", 13),
+		timed("Office hours", "", 15),
+	}
+
+	ignore, _ := parseEventRule("summary=^Optional:", treatFree)
+	ignoreColor, _ := parseEventRule("color=8", treatFree)
+	soft, _ := parseEventRule("summary=(?i)office hours", treatSoft)
+	opts := eventOptions{rules: []eventRule{ignore, ignoreColor, soft}}
+
+	got := eventsToIntervals(events, loc, opts)
+	if len(got) != 2 {
+		t.Fatalf("eventsToIntervals() = %v, want 2 intervals", got)
+	}
+	if got[0].start.Hour() != 13 || got[0].soft {
+		t.Errorf("eventsToIntervals()[0] = %v, want the design review as busy", got[0])
+	}
+	if got[1].start.Hour() != 15 || !got[1].soft {
+		t.Errorf("eventsToIntervals()[1] = %v, want office hours as soft", got[1])
+	}
+
+	var buf bytes.Buffer
+	rules := []eventRule{ignore, ignoreColor, soft}
+	unused, _ := parseEventRule("type=focusTime", treatFree)
+	rules = append(rules, unused)
+	if err := writeRuleMatches(&buf, rules, []sourceEvents{{source: "primary", events: events}}, loc); err != nil {
+		t.Fatalf("writeRuleMatches() error = %v", err)
+	}
+	want := "ignore summary=^Optional:\n" +
+		"  2025-01-13 10:00 Optional: all-hands watch party (primary)\n" +
+		"\n" +
+		"ignore color=8\n" +
+		"  2025-01-13 11:00 1on1 (primary)\n" +
+		"\n" +
+		"soft summary=(?i)office hours\n" +
+		"  2025-01-13 15:00 Office hours (primary)\n" +
+		"\n" +
+		"ignore type=focusTime\n" +
+		"  (no events)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeRuleMatches() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRuleFlagOrder(t *testing.T) {
+	var specs []ruleSpec
+	fset := flag.NewFlagSet("freecal", flag.ContinueOnError)
+	fset.Var(&ruleFlag{action: treatFree, specs: &specs}, "ignore", "")
+	fset.Var(&ruleFlag{action: treatSoft, specs: &specs}, "soft", "")
+	args := []string{"-soft", "summary=(?i)office hours", "-ignore", "summary=hours", "-soft", "color=8,9"}
+	if err := fset.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	want := []ruleSpec{
+		{action: treatSoft, spec: "summary=(?i)office hours"},
+		{action: treatFree, spec: "summary=hours"},
+		{action: treatSoft, spec: "color=8,9"},
+	}
+	if !reflect.DeepEqual(specs, want) {
+		t.Errorf("specs = %v, want %v", specs, want)
+	}
+	if got := listFlagValues(fset.Lookup("soft").Value); !reflect.DeepEqual(got, []string{"summary=(?i)office hours", "color=8,9"}) {
+		t.Errorf("soft values = %q", got)
+	}
+
+	// the -soft rule given first wins over the later -ignore rule
+	var rules []eventRule
+	for _, s := range specs {
+		r, err := parseEventRule(s.spec, s.action)
+		if err != nil {
+			t.Fatalf("parseEventRule(%q) error = %v", s.spec, err)
+		}
+		rules = append(rules, r)
+	}
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	start := time.Date(2025, 1, 13, 15, 0, 0, 0, loc)
+	e := &calendar.Event{
+		Summary: "Office hours",
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+	}
+	got := eventsToIntervals([]*calendar.Event{e}, loc, eventOptions{rules: rules})
+	if len(got) != 1 || !got[0].soft {
+		t.Errorf("eventsToIntervals() = %v, want office hours as soft", got)
+	}
+}
+
+func TestRulesKeepDeclinedFree(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	start := time.Date(2025, 1, 13, 10, 0, 0, 0, loc)
+	declined := &calendar.Event{
+		Summary:   "Office hours",
+		Start:     &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:       &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+		Attendees: []*calendar.EventAttendee{{Email: "me@example.com", Self: true, ResponseStatus: "declined"}},
+	}
+	soft, _ := parseEventRule("summary=(?i)office hours", treatSoft)
+	rules := []eventRule{soft}
+
+	if got := eventsToIntervals([]*calendar.Event{declined}, loc, eventOptions{rules: rules}); len(got) != 0 {
+		t.Errorf("eventsToIntervals() = %v, want the declined event to stay free", got)
+	}
+	if i := matchRule(rules, "primary", declined); i != -1 {
+		t.Errorf("matchRule() = %d, want -1 for a declined event", i)
+	}
+}
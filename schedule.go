@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdaySet is a set of days of the week, indexed by time.Weekday.
+type weekdaySet [7]bool
+
+func (s weekdaySet) has(wd time.Weekday) bool {
+	return s[wd]
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) >= 3 {
+		if wd, ok := weekdayNames[s[:3]]; ok && strings.HasPrefix(strings.ToLower(wd.String()), s) {
+			return wd, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", s)
+}
+
+// parseWeekdays parses a comma-separated list of weekdays and ranges, such
+// as "mon,tue,wed,thu,fri" or "sun-thu". Ranges may wrap around the end of
+// the week, e.g. "fri-mon".
+func parseWeekdays(s string) (weekdaySet, error) {
+	var set weekdaySet
+	if strings.TrimSpace(s) == "" {
+		return set, fmt.Errorf("no weekdays given")
+	}
+	for _, part := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseWeekday(from)
+		if err != nil {
+			return set, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseWeekday(to); err != nil {
+				return set, err
+			}
+		}
+		for wd := first; ; wd = (wd + 1) % 7 {
+			set[wd] = true
+			if wd == last {
+				break
+			}
+		}
+	}
+	return set, nil
+}
+
+// clockWindow is a time-of-day range such as 09:00-17:00, in minutes since
+// midnight. end may be 24:00.
+type clockWindow struct {
+	start int
+	end   int
+}
+
+// parseClockMinutes parses HH:MM into minutes since midnight. Unlike
+// mustParseClock it accepts 24:00 for the end of the day.
+func parseClockMinutes(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (want HH:MM)", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseClockWindow(s string) (clockWindow, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return clockWindow{}, fmt.Errorf("invalid time range %q (want HH:MM-HH:MM)", s)
+	}
+	start, err := parseClockMinutes(from)
+	if err != nil {
+		return clockWindow{}, err
+	}
+	end, err := parseClockMinutes(to)
+	if err != nil {
+		return clockWindow{}, err
+	}
+	if end <= start {
+		return clockWindow{}, fmt.Errorf("invalid time range %q: end is not after start", s)
+	}
+	return clockWindow{start: start, end: end}, nil
+}
+
+// on returns the window on the given day in loc.
+func (w clockWindow) on(day time.Time, loc *time.Location) interval {
+	return interval{
+		start: time.Date(day.Year(), day.Month(), day.Day(), 0, w.start, 0, 0, loc),
+		end:   time.Date(day.Year(), day.Month(), day.Day(), 0, w.end, 0, 0, loc),
+	}
+}
+
+// weeklyHours holds the working windows of each day of the week.
+type weeklyHours [7][]clockWindow
+
+// parseWeeklyHours parses a schedule such as
+// "mon=10:00-18:00,fri=09:00-15:00". Days may be ranges ("mon-thu=...")
+// and may be repeated to give more than one window per day. Days not
+// mentioned work during def.
+func parseWeeklyHours(s string, def clockWindow) (weeklyHours, error) {
+	var h weeklyHours
+	var set weekdaySet
+	if strings.TrimSpace(s) != "" {
+		for _, part := range strings.Split(s, ",") {
+			days, window, ok := strings.Cut(part, "=")
+			if !ok {
+				return h, fmt.Errorf("invalid hours %q (want DAYS=HH:MM-HH:MM)", part)
+			}
+			wds, err := parseWeekdays(days)
+			if err != nil {
+				return h, err
+			}
+			w, err := parseClockWindow(window)
+			if err != nil {
+				return h, err
+			}
+			for wd := range wds {
+				if wds[wd] {
+					h[wd] = append(h[wd], w)
+					set[wd] = true
+				}
+			}
+		}
+	}
+	for wd := range h {
+		if !set[wd] {
+			h[wd] = []clockWindow{def}
+		}
+	}
+	return h, nil
+}
+
+// windows returns the working windows of day in loc, with overlapping and
+// adjacent windows merged.
+func (h weeklyHours) windows(day time.Time, loc *time.Location) []interval {
+	ws := h[day.Weekday()]
+	out := make([]interval, 0, len(ws))
+	for _, w := range ws {
+		out = append(out, w.on(day, loc))
+	}
+	return mergeIntervals(out)
+}
+
+// exclusion is a recurring block of personal time, such as a lunch break,
+// that is treated as busy on the given weekdays.
+type exclusion struct {
+	days   weekdaySet
+	window clockWindow
+}
+
+// parseExclusion parses "HH:MM-HH:MM" (every day) or "DAYS=HH:MM-HH:MM",
+// e.g. "12:00-13:00" or "mon-fri=09:30-09:45".
+func parseExclusion(s string) (exclusion, error) {
+	days := weekdaySet{true, true, true, true, true, true, true}
+	window := s
+	if d, w, ok := strings.Cut(s, "="); ok {
+		var err error
+		if days, err = parseWeekdays(d); err != nil {
+			return exclusion{}, err
+		}
+		window = w
+	}
+	w, err := parseClockWindow(window)
+	if err != nil {
+		return exclusion{}, err
+	}
+	return exclusion{days: days, window: w}, nil
+}
+
+// exclusionIntervals returns the busy intervals of the exclusions on every
+// day from startDate through endDate.
+func exclusionIntervals(exclusions []exclusion, startDate, endDate time.Time, loc *time.Location) []interval {
+	var out []interval
+	for day := startDate; !day.After(endDate); day = day.AddDate(0, 0, 1) {
+		for _, x := range exclusions {
+			if x.days.has(day.Weekday()) {
+				out = append(out, x.window.on(day, loc))
+			}
+		}
+	}
+	return out
+}
+
+// workSchedule is when somebody works: their working days and hours in
+// their own timezone, except on holidays.
+type workSchedule struct {
+	loc       *time.Location
+	workdays  weekdaySet
+	hours     weeklyHours
+	isHoliday holidayFunc
+}
+
+// workingIntervals returns the working windows overlapping [from, to) as
+// absolute times, clipped to the range, so that the schedules of people in
+// different timezones can be intersected on one timeline.
+func (s workSchedule) workingIntervals(from, to time.Time) []interval {
+	rng := interval{start: from, end: to}
+	first := from.In(s.loc)
+	var out []interval
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, s.loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !s.workdays.has(day.Weekday()) {
+			continue
+		}
+		if _, ok := s.isHoliday(day); ok {
+			continue
+		}
+		for _, w := range s.hours.windows(day, s.loc) {
+			if inter, ok := overlaps(w, rng); ok {
+				out = append(out, inter)
+			}
+		}
+	}
+	return mergeIntervals(out)
+}
+
+// intersectIntervals returns the times contained in both a and b, which must
+// be sorted and must not overlap themselves (as returned by mergeIntervals).
+func intersectIntervals(a, b []interval) []interval {
+	var out []interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		if inter, ok := overlaps(a[i], b[j]); ok {
+			out = append(out, inter)
+		}
+		if a[i].end.Before(b[j].end) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+// participant is a calendar whose owner works in their own timezone and
+// hours (-participant). A slot is only proposed when it is within the
+// working hours of every participant.
+type participant struct {
+	calendar string
+	schedule workSchedule
+}
+
+// parseParticipant parses key=value pairs separated by semicolons, e.g.
+// "calendar=bob@example.com;tz=Europe/London;hours=09:00-17:30". Keys other
+// than calendar are optional and default to def:
+//
+//   - tz: IANA timezone
+//   - hours: HH:MM-HH:MM, or per weekday as in -hours (other days work
+//     during defWindow)
+//   - workdays: as in -workdays
+//   - holidays: as in -holidays
+func parseParticipant(spec string, def workSchedule, defWindow clockWindow) (participant, error) {
+	p := participant{schedule: def}
+	for _, part := range strings.Split(spec, ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		value = strings.TrimSpace(value)
+		if !ok || value == "" {
+			return p, fmt.Errorf("invalid setting %q in %q (want key=value)", part, spec)
+		}
+		var err error
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "calendar":
+			p.calendar = value
+		case "tz":
+			p.schedule.loc, err = time.LoadLocation(value)
+		case "hours":
+			if strings.Contains(value, "=") {
+				p.schedule.hours, err = parseWeeklyHours(value, defWindow)
+			} else {
+				var w clockWindow
+				if w, err = parseClockWindow(value); err == nil {
+					p.schedule.hours, err = parseWeeklyHours("", w)
+				}
+			}
+		case "workdays":
+			p.schedule.workdays, err = parseWeekdays(value)
+		case "holidays":
+			p.schedule.isHoliday, err = lookupHolidayCalendar(value)
+		default:
+			return p, fmt.Errorf("unknown key %q in %q (want calendar, tz, hours, workdays or holidays)", key, spec)
+		}
+		if err != nil {
+			return p, fmt.Errorf("invalid %s in %q: %w", strings.TrimSpace(key), spec, err)
+		}
+	}
+	if p.calendar == "" {
+		return p, fmt.Errorf("no calendar in %q", spec)
+	}
+	return p, nil
+}
@@ -0,0 +1,395 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWeekdays(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []time.Weekday
+		wantErr bool
+	}{
+		{
+			name:  "list",
+			input: "mon,tue,wed,thu,fri",
+			want:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		},
+		{
+			name:  "range",
+			input: "sun-thu",
+			want:  []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday},
+		},
+		{
+			name:  "wrapping range",
+			input: "fri-mon",
+			want:  []time.Weekday{time.Sunday, time.Monday, time.Friday, time.Saturday},
+		},
+		{
+			name:  "mixed, full names and case",
+			input: "Tuesday-Thu, SAT",
+			want:  []time.Weekday{time.Tuesday, time.Wednesday, time.Thursday, time.Saturday},
+		},
+		{
+			name:  "single day range",
+			input: "wed-wed",
+			want:  []time.Weekday{time.Wednesday},
+		},
+		{
+			name:    "unknown name",
+			input:   "mon,funday",
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			input:   "mo",
+			wantErr: true,
+		},
+		{
+			name:    "empty",
+			input:   "",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWeekdays(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWeekdays(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var want weekdaySet
+			for _, wd := range tt.want {
+				want[wd] = true
+			}
+			if got != want {
+				t.Errorf("parseWeekdays(%q) = %v, want %v", tt.input, got, want)
+			}
+		})
+	}
+}
+
+func TestParseWeeklyHours(t *testing.T) {
+	def := clockWindow{start: 9 * 60, end: 17 * 60}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    map[time.Weekday][]clockWindow
+		wantErr bool
+	}{
+		{
+			name:  "empty uses default",
+			input: "",
+			want: map[time.Weekday][]clockWindow{
+				time.Monday: {def},
+				time.Friday: {def},
+			},
+		},
+		{
+			name:  "per weekday",
+			input: "mon=10:00-18:00,fri=09:00-15:00",
+			want: map[time.Weekday][]clockWindow{
+				time.Monday:  {{start: 10 * 60, end: 18 * 60}},
+				time.Tuesday: {def},
+				time.Friday:  {{start: 9 * 60, end: 15 * 60}},
+			},
+		},
+		{
+			name:  "ranges and several windows",
+			input: "tue-thu=08:30-12:00,tue-thu=13:00-24:00",
+			want: map[time.Weekday][]clockWindow{
+				time.Monday:    {def},
+				time.Wednesday: {{start: 8*60 + 30, end: 12 * 60}, {start: 13 * 60, end: 24 * 60}},
+			},
+		},
+		{
+			name:    "missing window",
+			input:   "mon",
+			wantErr: true,
+		},
+		{
+			name:    "end before start",
+			input:   "mon=18:00-10:00",
+			wantErr: true,
+		},
+		{
+			name:    "invalid time",
+			input:   "mon=9-17",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWeeklyHours(tt.input, def)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseWeeklyHours(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			for wd, want := range tt.want {
+				if !reflect.DeepEqual(got[wd], want) {
+					t.Errorf("parseWeeklyHours(%q)[%s] = %v, want %v", tt.input, wd, got[wd], want)
+				}
+			}
+		})
+	}
+}
+
+func TestWeeklyHoursWindows(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	h, err := parseWeeklyHours("mon=09:00-12:00,mon=11:00-13:00,mon=14:00-24:00", clockWindow{})
+	if err != nil {
+		t.Fatalf("parseWeeklyHours() error = %v", err)
+	}
+	monday := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+	got := h.windows(monday, loc)
+	want := []interval{
+		{start: time.Date(2025, 1, 13, 9, 0, 0, 0, loc), end: time.Date(2025, 1, 13, 13, 0, 0, 0, loc)},
+		{start: time.Date(2025, 1, 13, 14, 0, 0, 0, loc), end: time.Date(2025, 1, 14, 0, 0, 0, 0, loc)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("windows() = %v, want %v", got, want)
+	}
+}
+
+func TestParseExclusion(t *testing.T) {
+	everyDay := weekdaySet{true, true, true, true, true, true, true}
+	tests := []struct {
+		input   string
+		want    exclusion
+		wantErr bool
+	}{
+		{
+			input: "12:00-13:00",
+			want:  exclusion{days: everyDay, window: clockWindow{start: 12 * 60, end: 13 * 60}},
+		},
+		{
+			input: "mon-wed=09:30-09:45",
+			want: exclusion{
+				days:   weekdaySet{time.Monday: true, time.Tuesday: true, time.Wednesday: true},
+				window: clockWindow{start: 9*60 + 30, end: 9*60 + 45},
+			},
+		},
+		{
+			input: "mon,wed,fri=09:30-09:45",
+			want: exclusion{
+				days:   weekdaySet{time.Monday: true, time.Wednesday: true, time.Friday: true},
+				window: clockWindow{start: 9*60 + 30, end: 9*60 + 45},
+			},
+		},
+		{input: "lunch=12:00-13:00", wantErr: true},
+		{input: "12:00", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseExclusion(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExclusion(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseExclusion(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExclusionsSplitFreeSlots(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	monday := time.Date(2025, 1, 13, 0, 0, 0, 0, loc)
+	tuesday := monday.AddDate(0, 0, 1)
+
+	lunch, _ := parseExclusion("12:00-13:00")
+	standup, _ := parseExclusion("mon=09:30-09:45")
+	busy := exclusionIntervals([]exclusion{lunch, standup}, monday, tuesday, loc)
+	busy = append(busy, interval{
+		start: time.Date(2025, 1, 13, 12, 30, 0, 0, loc),
+		end:   time.Date(2025, 1, 13, 14, 0, 0, 0, loc),
+	})
+
+	tests := []struct {
+		day  time.Time
+		want []string
+	}{
+		{monday, []string{"09:00~09:30", "09:45~12:00", "14:00~17:00"}},
+		{tuesday, []string{"09:00~12:00", "13:00~17:00"}},
+	}
+	for _, tt := range tests {
+		got := findFreeSlots(
+			time.Date(tt.day.Year(), tt.day.Month(), tt.day.Day(), 9, 0, 0, 0, loc),
+			time.Date(tt.day.Year(), tt.day.Month(), tt.day.Day(), 17, 0, 0, 0, loc),
+			busy, slotOptions{minDur: 30 * time.Minute})
+		if len(got) != len(tt.want) {
+			t.Fatalf("findFreeSlots(%s) = %v, want %v", tt.day.Format("2006-01-02"), got, tt.want)
+		}
+		for i, s := range got {
+			if formatSlot(s) != tt.want[i] {
+				t.Errorf("findFreeSlots(%s)[%d] = %s, want %s", tt.day.Format("2006-01-02"), i, formatSlot(s), tt.want[i])
+			}
+		}
+	}
+}
+
+func TestParseParticipant(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	nineToFive := clockWindow{start: 9 * 60, end: 17 * 60}
+	defHours, _ := parseWeeklyHours("", nineToFive)
+	def := workSchedule{
+		loc:       tokyo,
+		workdays:  weekdaySet{false, true, true, true, true, true, false},
+		hours:     defHours,
+		isHoliday: jpHoliday,
+	}
+
+	tests := []struct {
+		name      string
+		spec      string
+		wantTZ    string
+		wantMon   []clockWindow
+		wantFri   []clockWindow
+		wantSun   bool
+		wantError bool
+	}{
+		{
+			name:    "defaults",
+			spec:    "calendar=primary",
+			wantTZ:  "Asia/Tokyo",
+			wantMon: []clockWindow{nineToFive},
+			wantFri: []clockWindow{nineToFive},
+		},
+		{
+			name:    "timezone and hours",
+			spec:    "calendar=bob@example.com;tz=Europe/London;hours=08:00-16:30",
+			wantTZ:  "Europe/London",
+			wantMon: []clockWindow{{start: 8 * 60, end: 16*60 + 30}},
+			wantFri: []clockWindow{{start: 8 * 60, end: 16*60 + 30}},
+		},
+		{
+			name:    "weekly hours and workdays",
+			spec:    "calendar=dan@example.com;tz=Asia/Dubai;hours=fri=09:00-12:00;workdays=sun-thu,fri",
+			wantTZ:  "Asia/Dubai",
+			wantMon: []clockWindow{nineToFive},
+			wantFri: []clockWindow{{start: 9 * 60, end: 12 * 60}},
+			wantSun: true,
+		},
+		{name: "no calendar", spec: "tz=Europe/London", wantError: true},
+		{name: "unknown timezone", spec: "calendar=primary;tz=Mars/Olympus", wantError: true},
+		{name: "unknown key", spec: "calendar=primary;room=A", wantError: true},
+		{name: "invalid hours", spec: "calendar=primary;hours=17:00-09:00", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := parseParticipant(tt.spec, def, nineToFive)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("parseParticipant(%q) error = %v, wantError %v", tt.spec, err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+			if got := p.schedule.loc.String(); got != tt.wantTZ {
+				t.Errorf("tz = %s, want %s", got, tt.wantTZ)
+			}
+			if got := p.schedule.hours[time.Monday]; !reflect.DeepEqual(got, tt.wantMon) {
+				t.Errorf("Monday hours = %v, want %v", got, tt.wantMon)
+			}
+			if got := p.schedule.hours[time.Friday]; !reflect.DeepEqual(got, tt.wantFri) {
+				t.Errorf("Friday hours = %v, want %v", got, tt.wantFri)
+			}
+			if got := p.schedule.workdays.has(time.Sunday); got != tt.wantSun {
+				t.Errorf("works on Sunday = %v, want %v", got, tt.wantSun)
+			}
+		})
+	}
+}
+
+func TestParticipantsWorkingIntersection(t *testing.T) {
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	london, _ := time.LoadLocation("Europe/London")
+	newYork, _ := time.LoadLocation("America/New_York")
+	monFri := weekdaySet{false, true, true, true, true, true, false}
+	schedule := func(loc *time.Location, start, end int) workSchedule {
+		h, _ := parseWeeklyHours("", clockWindow{start: start * 60, end: end * 60})
+		return workSchedule{loc: loc, workdays: monFri, hours: h, isHoliday: holidayCalendars["none"]}
+	}
+	from := time.Date(2025, 1, 13, 0, 0, 0, 0, tokyo) // Monday
+	to := from.AddDate(0, 0, 2)
+	at := func(day, h int) time.Time { return time.Date(2025, 1, day, h, 0, 0, 0, tokyo) }
+
+	tests := []struct {
+		name   string
+		others []workSchedule
+		want   []interval
+	}{
+		{
+			name: "alone",
+			want: []interval{{start: at(13, 9), end: at(13, 19)}, {start: at(14, 9), end: at(14, 19)}},
+		},
+		{
+			name:   "London morning is Tokyo evening",
+			others: []workSchedule{schedule(london, 8, 17)},
+			want:   []interval{{start: at(13, 17), end: at(13, 19)}, {start: at(14, 17), end: at(14, 19)}},
+		},
+		{
+			// 08:00-18:00 in New York is 22:00-08:00 of the next day in Tokyo
+			name:   "New York evening is Tokyo next morning",
+			others: []workSchedule{schedule(newYork, 8, 18)},
+			want:   nil,
+		},
+		{
+			name:   "nobody overlaps with everyone",
+			others: []workSchedule{schedule(london, 8, 17), schedule(newYork, 8, 18)},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schedule(tokyo, 9, 19).workingIntervals(from, to)
+			for _, o := range tt.others {
+				got = intersectIntervals(got, o.workingIntervals(from, to))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("working = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if !got[i].start.Equal(tt.want[i].start) || !got[i].end.Equal(tt.want[i].end) {
+					t.Errorf("working[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	// an early start in Tokyo meets the end of the day in New York
+	got := intersectIntervals(
+		schedule(tokyo, 7, 10).workingIntervals(from, to),
+		schedule(newYork, 8, 18).workingIntervals(from, to),
+	)
+	want := interval{start: at(14, 7), end: at(14, 8)}
+	if len(got) != 1 || !got[0].start.Equal(want.start) || !got[0].end.Equal(want.end) {
+		t.Errorf("working = %v, want [%v]", got, want)
+	}
+}
+
+func TestIntersectIntervals(t *testing.T) {
+	at := func(h int) time.Time { return time.Date(2025, 1, 13, h, 0, 0, 0, time.UTC) }
+	a := []interval{{start: at(1), end: at(5)}, {start: at(8), end: at(12)}}
+	b := []interval{{start: at(0), end: at(2)}, {start: at(4), end: at(9)}, {start: at(11), end: at(13)}}
+	got := intersectIntervals(a, b)
+	want := []interval{
+		{start: at(1), end: at(2)},
+		{start: at(4), end: at(5)},
+		{start: at(8), end: at(9)},
+		{start: at(11), end: at(12)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("intersectIntervals() = %v, want %v", got, want)
+	}
+	if got := intersectIntervals(a, nil); got != nil {
+		t.Errorf("intersectIntervals(a, nil) = %v, want nil", got)
+	}
+}
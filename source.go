@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	calendar "google.golang.org/api/calendar/v3"
+)
+
+// eventSource provides the events of a single calendar. Events of every
+// source are converted to busy intervals by eventsToIntervals, so non-Google
+// sources produce calendar.Event values as well.
+type eventSource interface {
+	// name identifies the source in log and error messages.
+	name() string
+	fetchEvents(ctx context.Context, startDate, endDate time.Time, loc *time.Location) ([]*calendar.Event, error)
+}
+
+// busySource provides busy intervals directly, for backends that only expose
+// free/busy information and no event details.
+type busySource interface {
+	// name identifies the source in log and error messages.
+	name() string
+	fetchBusy(ctx context.Context, startDate, endDate time.Time, loc *time.Location) ([]interval, error)
+}
+
+// googleSource reads events from Google Calendar.
+type googleSource struct {
+	svc        *calendar.Service
+	calendarID string
+}
+
+func (s *googleSource) name() string {
+	return s.calendarID
+}
+
+func (s *googleSource) fetchEvents(
+	ctx context.Context,
+	startDate, endDate time.Time,
+	loc *time.Location,
+) ([]*calendar.Event, error) {
+	return fetchCalendarEvents(ctx, s.svc, s.calendarID, startDate, endDate, loc)
+}
+
+// queryRange returns the half-open time range [timeMin, timeMax) covering
+// the days from startDate through endDate in loc.
+func queryRange(startDate, endDate time.Time, loc *time.Location) (timeMin, timeMax time.Time) {
+	timeMin = time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc)
+	timeMax = time.Date(endDate.Year(), endDate.Month(), endDate.Day()+1, 0, 0, 0, 0, loc)
+	return timeMin, timeMax
+}
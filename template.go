@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateData is the model that -template templates are executed with.
+// Like the JSON schema, it is a public interface: only add fields.
+type templateData struct {
+	// Days are the days with at least one free slot.
+	Days []templateDay
+	// Timezone is the IANA name of the timezone of all times.
+	Timezone string
+}
+
+type templateDay struct {
+	Date time.Time
+	// Weekday is the short weekday name in the -lang language.
+	Weekday string
+	// Heading is the date and weekday in the -lang language, e.g.
+	// "2025-01-13（月）" or "Mon, Jan 13".
+	Heading string
+	Slots   []templateSlot
+	// Soft are the soft busy times of the day (see -tentative).
+	Soft []templateSlot
+}
+
+type templateSlot struct {
+	Start    time.Time
+	End      time.Time
+	Duration time.Duration
+	// Minutes is Duration in whole minutes.
+	Minutes int
+	// Display is the slot in each of the -display-tz timezones.
+	Display []templateZonedSlot
+}
+
+type templateZonedSlot struct {
+	// Timezone is the IANA name of the zone.
+	Timezone string
+	// Abbreviation and Offset label the zone as of the slot, e.g. "PST"
+	// and "UTC-08:00".
+	Abbreviation string
+	Offset       string
+	Start        time.Time
+	End          time.Time
+	// DayShift is the number of days the date in the zone differs from
+	// the day the slot is listed under.
+	DayShift int
+}
+
+func newTemplateData(days []daySlots, opts outputOptions) templateData {
+	loc, lang := opts.loc, opts.lang
+	toSlots := func(in []interval, day time.Time) []templateSlot {
+		out := make([]templateSlot, 0, len(in))
+		for _, s := range in {
+			ts := templateSlot{
+				Start:    s.start.In(loc),
+				End:      s.end.In(loc),
+				Duration: s.end.Sub(s.start),
+				Minutes:  int(s.end.Sub(s.start) / time.Minute),
+			}
+			for _, zone := range opts.displayZones {
+				z := convertSlot(s, day, zone)
+				ts.Display = append(ts.Display, templateZonedSlot{
+					Timezone:     zone.String(),
+					Abbreviation: z.abbr,
+					Offset:       z.offset,
+					Start:        z.start,
+					End:          z.end,
+					DayShift:     z.dayShift,
+				})
+			}
+			out = append(out, ts)
+		}
+		return out
+	}
+
+	data := templateData{Days: make([]templateDay, 0, len(days)), Timezone: loc.String()}
+	for _, d := range days {
+		data.Days = append(data.Days, templateDay{
+			Date:    d.date,
+			Weekday: lang.weekday(d.date),
+			Heading: lang.day(d.date),
+			Slots:   toSlots(d.slots, d.date),
+			Soft:    toSlots(d.soft, d.date),
+		})
+	}
+	return data
+}
+
+// templateFuncs are the functions available in templates in addition to the
+// text/template builtins.
+func templateFuncs(lang *locale) template.FuncMap {
+	toIntervals := func(ss []templateSlot) []interval {
+		out := make([]interval, 0, len(ss))
+		for _, s := range ss {
+			out = append(out, interval{start: s.Start, end: s.End})
+		}
+		return out
+	}
+	return template.FuncMap{
+		// clock formats a time as "15:04".
+		"clock": func(t time.Time) string { return t.Format("15:04") },
+		// slot formats a slot as "09:00~10:00" in the -lang language.
+		"slot": func(s templateSlot) string { return lang.slot(interval{start: s.Start, end: s.End}) },
+		// slots formats a list of slots, e.g. "09:00~10:00, 14:00~15:30".
+		"slots": func(ss []templateSlot) string { return lang.slots(toIntervals(ss)) },
+		// soft formats the soft busy times of a day, e.g. "（仮予定: 13:00~14:00）",
+		// or returns "" when there are none.
+		"soft": func(ss []templateSlot) string {
+			if len(ss) == 0 {
+				return ""
+			}
+			return fmt.Sprintf(lang.softFormat, lang.slots(toIntervals(ss)))
+		},
+		// sep is the separator between a day heading and its slots.
+		"sep": func() string { return lang.headingSep },
+		// noSlots is the "no free slots" message in the -lang language.
+		"noSlots": func() string { return lang.noSlots },
+	}
+}
+
+// builtinTemplates can be selected by name with -template.
+var builtinTemplates = map[string]string{
+	"markdown": `{{range .Days -}}
+- {{.Heading}}{{sep}}{{slots .Slots}}{{soft .Soft}}
+{{else -}}
+{{noSlots}}
+{{end -}}`,
+	"table": `| Date | Weekday | Start | End | Minutes |
+|------|---------|-------|-----|---------|
+{{range $d := .Days}}{{range .Slots -}}
+| {{$d.Date.Format "2006-01-02"}} | {{$d.Weekday}} | {{clock .Start}} | {{clock .End}} | {{.Minutes}} |
+{{end}}{{end -}}`,
+	"plain": `{{range .Days -}}
+{{.Heading}}{{sep}}{{slots .Slots}}
+{{end -}}`,
+}
+
+func builtinTemplateNames() string {
+	names := make([]string, 0, len(builtinTemplates))
+	for n := range builtinTemplates {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+// loadTemplate parses the built-in template called name, or else the
+// template file at path name.
+func loadTemplate(name string, lang *locale) (*template.Template, error) {
+	text, ok := builtinTemplates[name]
+	if !ok {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read template (built-in templates are %s): %w", builtinTemplateNames(), err)
+		}
+		text = string(b)
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs(lang)).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func writeTemplate(w io.Writer, tmpl *template.Template, days []daySlots, opts outputOptions) error {
+	return tmpl.Execute(w, newTemplateData(days, opts))
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuiltinTemplates(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+
+	tests := []struct {
+		name string
+		lang *locale
+		days []daySlots
+		want string
+	}{
+		{
+			name: "markdown",
+			lang: &localeJa,
+			days: testDays(loc),
+			want: "- 2025-01-13（月） 09:00~10:00, 14:00~15:30\n" +
+				"- 2025-01-14（火） 10:30~12:00\n",
+		},
+		{
+			name: "markdown",
+			lang: &localeEn,
+			want: "No free slots.\n",
+		},
+		{
+			name: "table",
+			lang: &localeJa,
+			days: testDays(loc),
+			want: "| Date | Weekday | Start | End | Minutes |\n" +
+				"|------|---------|-------|-----|---------|\n" +
+				"| 2025-01-13 | 月 | 09:00 | 10:00 | 60 |\n" +
+				"| 2025-01-13 | 月 | 14:00 | 15:30 | 90 |\n" +
+				"| 2025-01-14 | 火 | 10:30 | 12:00 | 90 |\n",
+		},
+		{
+			name: "plain",
+			lang: &localeEn,
+			days: testDays(loc),
+			want: "Mon, Jan 13: 09:00–10:00, 14:00–15:30\n" +
+				"Tue, Jan 14: 10:30–12:00\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := loadTemplate(tt.name, tt.lang)
+			if err != nil {
+				t.Fatalf("loadTemplate(%q) error = %v", tt.name, err)
+			}
+			var buf bytes.Buffer
+			if err := writeTemplate(&buf, tmpl, tt.days, outputOptions{loc: loc, lang: tt.lang}); err != nil {
+				t.Fatalf("writeTemplate() error = %v", err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("writeTemplate() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// The markdown template must stay in sync with the default Markdown output.
+func TestMarkdownTemplateMatchesMarkdownOutput(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	days := testDays(loc)
+	days[1].soft = []interval{{
+		start: time.Date(2025, 1, 14, 13, 0, 0, 0, loc),
+		end:   time.Date(2025, 1, 14, 14, 0, 0, 0, loc),
+	}}
+
+	for _, lang := range locales {
+		tmpl, err := loadTemplate("markdown", lang)
+		if err != nil {
+			t.Fatalf("loadTemplate() error = %v", err)
+		}
+		var got, want bytes.Buffer
+		if err := writeTemplate(&got, tmpl, days, outputOptions{loc: loc, lang: lang}); err != nil {
+			t.Fatalf("writeTemplate() error = %v", err)
+		}
+		if err := writeOutput(&want, formatMarkdown, days, outputOptions{loc: loc, lang: lang}); err != nil {
+			t.Fatalf("writeOutput() error = %v", err)
+		}
+		if got.String() != want.String() {
+			t.Errorf("markdown template =\n%s\nwant\n%s", got.String(), want.String())
+		}
+	}
+}
+
+func TestTemplateFile(t *testing.T) {
+	loc, _ := time.LoadLocation("Asia/Tokyo")
+	path := filepath.Join(t.TempDir(), "slack.tmpl")
+	text := `{{range .Days}}*{{.Date.Format "1/2"}} ({{.Weekday}})*{{range .Slots}} • {{slot .}} ({{.Minutes}}m){{end}}
+{{end}}{{.Timezone}}
+`
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, err := loadTemplate(path, &localeJa)
+	if err != nil {
+		t.Fatalf("loadTemplate() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := writeTemplate(&buf, tmpl, testDays(loc), outputOptions{loc: loc, lang: &localeJa}); err != nil {
+		t.Fatalf("writeTemplate() error = %v", err)
+	}
+	want := "*1/13 (月)* • 09:00~10:00 (60m) • 14:00~15:30 (90m)\n" +
+		"*1/14 (火)* • 10:30~12:00 (90m)\n" +
+		"Asia/Tokyo\n"
+	if got := buf.String(); got != want {
+		t.Errorf("writeTemplate() =\n%s\nwant\n%s", got, want)
+	}
+
+	if _, err := loadTemplate(filepath.Join(t.TempDir(), "missing.tmpl"), &localeJa); err == nil {
+		t.Error("loadTemplate() of a missing file succeeded")
+	}
+	if err := os.WriteFile(path, []byte("{{range .Days}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTemplate(path, &localeJa); err == nil {
+		t.Error("loadTemplate() of an invalid template succeeded")
+	}
+}